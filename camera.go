@@ -0,0 +1,119 @@
+package main
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/murilo-toddy/copooter/render"
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+var (
+	minZoom = float32(0.1)
+	maxZoom = float32(4)
+	// Zoom change of each mouse wheel step
+	zoomStep = float32(1.1)
+	// Space left around the circuit when zooming to fit
+	zoomToFitMargin = render.GridCellSize * 4
+	// Grid lines closer than this on screen are skipped
+	minGridLineSpacing = float32(8)
+)
+
+// Camera over the schematic. At its default the world matches the screen, so
+// every hit test on placed components maps the mouse through it
+var camera = rl.Camera2D{Zoom: 1}
+
+func screenToWorld(pos rl.Vector2) rl.Vector2 {
+	return rl.GetScreenToWorld2D(pos, camera)
+}
+
+// Whether the screen position is over the canvas rather than the toolkit or
+// the hierarchy panel
+func isOverCanvas(s *DrawingState, pos rl.Vector2) bool {
+	return int32(pos.X) > toolkitSidebarSize && !(s.hierarchyVisible && isInsideHierarchyPanel(pos))
+}
+
+// Zooms by factor keeping the world position under pos in place
+func zoomAt(pos rl.Vector2, factor float32) {
+	world := screenToWorld(pos)
+	camera.Offset = pos
+	camera.Target = world
+	camera.Zoom = min(max(camera.Zoom*factor, minZoom), maxZoom)
+}
+
+// The mouse wheel zooms around the cursor, dragging with the middle button
+// pans and Home or the Fit button zooms to fit the whole circuit
+func checkCameraControls(s *DrawingState, pos rl.Vector2) {
+	if isOverCanvas(s, pos) {
+		if wheel := rl.GetMouseWheelMove(); wheel != 0 {
+			factor := zoomStep
+			if wheel < 0 {
+				factor = 1 / zoomStep
+			}
+			zoomAt(pos, factor)
+		}
+	}
+	if rl.IsMouseButtonDown(rl.MouseButtonMiddle) {
+		delta := rl.GetMouseDelta()
+		camera.Target.X -= delta.X / camera.Zoom
+		camera.Target.Y -= delta.Y / camera.Zoom
+	}
+	if isTextButtonClicked(pos, 4) || rl.IsKeyPressed(rl.KeyHome) {
+		zoomToFit(s.components)
+	}
+}
+
+// Centers the components on the canvas, as large as fits
+func zoomToFit(components []sim.Component) {
+	if len(components) == 0 {
+		camera = rl.Camera2D{Zoom: 1}
+		return
+	}
+	minX, minY := components[0].GetPosition()
+	maxX, maxY := minX, minY
+	for _, component := range components {
+		x, y := component.GetPosition()
+		minX, minY = min(minX, x), min(minY, y)
+		maxX, maxY = max(maxX, x), max(maxY, y)
+	}
+	// room for the component images and the names below them
+	maxX += render.ComponentImageSize
+	maxY += render.ComponentImageSize + render.ComponentFontSize
+	boundsWidth := float32(maxX - minX + 2*zoomToFitMargin)
+	boundsHeight := float32(maxY - minY + 2*zoomToFitMargin)
+
+	canvasWidth := float32(width - toolkitSidebarSize)
+	canvasHeight := float32(height)
+	camera.Zoom = min(max(min(canvasWidth/boundsWidth, canvasHeight/boundsHeight), minZoom), maxZoom)
+	camera.Offset = rl.Vector2{X: float32(toolkitSidebarSize) + canvasWidth/2, Y: canvasHeight / 2}
+	camera.Target = rl.Vector2{X: float32(minX+maxX) / 2, Y: float32(minY+maxY) / 2}
+}
+
+// Rounds v down to a multiple of step, also when negative
+func floorTo(v, step int32) int32 {
+	return v - (v%step+step)%step
+}
+
+// Snaps world coordinates to the grid, whose first vertical line is at the
+// toolkit sidebar when the camera is at its default
+func snapCoordinates(x, y int32) (int32, int32) {
+	cell := render.GridCellSize
+	return floorTo(x-toolkitSidebarSize, cell) + toolkitSidebarSize, floorTo(y, cell)
+}
+
+// Draws the grid lines visible on the canvas, in world coordinates
+func drawGridLines() {
+	topLeft := screenToWorld(rl.Vector2{X: float32(toolkitSidebarSize), Y: 0})
+	bottomRight := screenToWorld(rl.Vector2{X: float32(width), Y: float32(height)})
+	step := render.GridCellSize
+	for float32(step)*camera.Zoom < minGridLineSpacing {
+		step *= 2
+	}
+	color := rl.NewColor(48, 48, 48, 255)
+	startX := floorTo(int32(topLeft.X)-toolkitSidebarSize, step) + toolkitSidebarSize
+	startY := floorTo(int32(topLeft.Y), step)
+	for x := startX; x <= int32(bottomRight.X)+step; x += step {
+		rl.DrawLine(x, int32(topLeft.Y), x, int32(bottomRight.Y)+1, color)
+	}
+	for y := startY; y <= int32(bottomRight.Y)+step; y += step {
+		rl.DrawLine(int32(topLeft.X), y, int32(bottomRight.X)+1, y, color)
+	}
+}
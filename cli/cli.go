@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+// Headless subcommands, run instead of the GUI when named as the first
+// argument. They return the process exit code
+var Commands = map[string]func(args []string, stdout, stderr io.Writer) int{
+	"repl":       replCommand,
+	"serve":      serveCommand,
+	"sim":        simCommand,
+	"test":       testVectorsCommand,
+	"truthtable": truthTableCommand,
+}
+
+const (
+	exitSuccess = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// Input value given on the command line, as a state name or a digit
+func parseInputValue(value string) (sim.NodeState, error) {
+	switch strings.ToLower(value) {
+	case "1", "on", "high", "true":
+		return sim.On, nil
+	case "0", "off", "low", "false":
+		return sim.Off, nil
+	}
+	return sim.Undefined, fmt.Errorf("invalid input value %q, use 1 or 0", value)
+}
+
+// Repeatable NAME=VALUE flag
+type assignments []string
+
+func (a *assignments) String() string {
+	return strings.Join(*a, ",")
+}
+
+func (a *assignments) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("expected NAME=VALUE but got %q", value)
+	}
+	*a = append(*a, value)
+	return nil
+}
+
+// Repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// Tracer writing to w, built from the trace flags
+func newTracer(level, format string, components []string, w io.Writer) (*sim.Tracer, error) {
+	traceLevel, err := sim.ParseTraceLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	var sink sim.TraceSink
+	switch format {
+	case "text":
+		sink = sim.NewTextSink(w)
+	case "json":
+		sink = sim.NewJSONSink(w)
+	default:
+		return nil, fmt.Errorf("unknown trace format %q, use text or json", format)
+	}
+	tracer := sim.NewTracer(traceLevel, sink)
+	tracer.Components = components
+	return tracer, nil
+}
+
+// Applies NAME=VALUE assignments to the inputs of netlist
+func setInputs(netlist *sim.Netlist, values []string) error {
+	for _, value := range values {
+		name, value, _ := strings.Cut(value, "=")
+		state, err := parseInputValue(value)
+		if err != nil {
+			return fmt.Errorf("input %s: %w", name, err)
+		}
+		if err := netlist.SetInput(name, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parses flags placed before or after the positional arguments
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+type simStep struct {
+	Step    int               `json:"step"`
+	Outputs map[string]string `json:"outputs"`
+}
+
+type simResult struct {
+	Circuit string            `json:"circuit"`
+	Inputs  map[string]string `json:"inputs"`
+	Steps   []simStep         `json:"steps"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// copooter sim FILE [--set NAME=VALUE]... [--steps N] [--format table|json] [--vcd FILE]
+// [--trace LEVEL] [--trace-format text|json] [--trace-component PATTERN]...
+func simCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("sim", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var inputs assignments
+	flags.Var(&inputs, "set", "sets input `NAME=VALUE`, can be repeated")
+	steps := flags.Int("steps", 1, "number of simulation steps")
+	format := flags.String("format", "table", "output format, table or json")
+	vcdPath := flags.String("vcd", "", "records the meters to a VCD `file`")
+	traceLevel := flags.String("trace", "off", "traces the simulation to stderr up to `level`, one of off, error, warn, info, debug or verbose")
+	traceFormat := flags.String("trace-format", "text", "trace format, text or json")
+	var traceComponents stringList
+	flags.Var(&traceComponents, "trace-component", "only traces the components matching the glob `pattern`, can be repeated")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: copooter sim FILE [--set NAME=VALUE]... [--steps N] [--format table|json] [--vcd FILE]")
+		fmt.Fprintln(stderr, "                    [--trace LEVEL] [--trace-format text|json] [--trace-component PATTERN]...")
+		flags.PrintDefaults()
+	}
+	positional, err := parseInterspersed(flags, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitSuccess
+	}
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 || *steps < 1 || *format != "table" && *format != "json" {
+		flags.Usage()
+		return exitUsage
+	}
+	tracer, err := newTracer(*traceLevel, *traceFormat, traceComponents, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitUsage
+	}
+
+	netlist, err := sim.LoadDesign(positional[0])
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitFailure
+	}
+	netlist.Circuit.SetTracer(tracer)
+	if err := setInputs(netlist, inputs); err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitUsage
+	}
+	var recorder *sim.VCDRecorder
+	if *vcdPath != "" {
+		recorder = sim.NewVCDRecorder()
+		recorder.WatchMeters(netlist.Circuit, netlist.Name)
+		netlist.Circuit.Record(recorder)
+	}
+
+	result := simResult{Circuit: netlist.Name, Inputs: map[string]string{}}
+	for _, input := range netlist.Inputs {
+		result.Inputs[input] = sim.StateDigit(netlist.Terminals[input].State())
+	}
+	var simulationErr error
+	for step := range *steps {
+		if simulationErr = netlist.Circuit.Tick(); simulationErr != nil {
+			result.Error = fmt.Sprintf("step %d: %s", step, simulationErr)
+			break
+		}
+		outputs := map[string]string{}
+		for _, output := range netlist.Outputs {
+			state, _ := netlist.Output(output)
+			outputs[output] = sim.StateDigit(state)
+		}
+		result.Steps = append(result.Steps, simStep{step, outputs})
+	}
+
+	if recorder != nil {
+		if err := writeVCDFile(*vcdPath, recorder); err != nil {
+			fmt.Fprintln(stderr, "error:", err)
+			return exitFailure
+		}
+	}
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(result)
+	default:
+		table := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "step\t"+strings.Join(netlist.Outputs, "\t"))
+		for _, step := range result.Steps {
+			row := []string{fmt.Sprint(step.Step)}
+			for _, output := range netlist.Outputs {
+				row = append(row, step.Outputs[output])
+			}
+			fmt.Fprintln(table, strings.Join(row, "\t"))
+		}
+		table.Flush()
+	}
+	if simulationErr != nil {
+		fmt.Fprintln(stderr, "error:", result.Error)
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+func writeVCDFile(path string, recorder *sim.VCDRecorder) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = recorder.WriteTo(f)
+	return err
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Half adder shared with the tests of the simulation library
+var testHalfAdderDesign = filepath.Join("..", "sim", "testdata", "half_adder.circuit")
+
+func writeTestFile(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSimCommand(t *testing.T) {
+	path := testHalfAdderDesign
+	tt := []struct {
+		args           []string
+		expectedCode   int
+		expectedOutput string
+	}{
+		{[]string{path, "--set", "a=1", "--set", "b=1"}, exitSuccess, "step  sum  carry\n0     0    1\n"},
+		{[]string{"--steps", "2", path, "--set", "a=1"}, exitSuccess, "step  sum  carry\n0     1    0\n1     1    0\n"},
+		{[]string{path, "--set", "c=1"}, exitUsage, ""},
+		{[]string{path, "--set", "a=2"}, exitUsage, ""},
+		{[]string{path, "--format", "xml"}, exitUsage, ""},
+		{[]string{path, "--trace", "loud"}, exitUsage, ""},
+		{[]string{path, "--trace", "info", "--trace-format", "xml"}, exitUsage, ""},
+		{[]string{filepath.Join(t.TempDir(), "missing.circuit")}, exitFailure, ""},
+	}
+	for _, tc := range tt {
+		var stdout, stderr strings.Builder
+		code := simCommand(tc.args, &stdout, &stderr)
+		if code != tc.expectedCode || stdout.String() != tc.expectedOutput {
+			t.Errorf("sim %v returned %d with output %q instead of %d with output %q, stderr: %s",
+				tc.args, code, stdout.String(), tc.expectedCode, tc.expectedOutput, stderr.String())
+		}
+	}
+}
+
+func TestSimCommandJSON(t *testing.T) {
+	path := testHalfAdderDesign
+	var stdout, stderr strings.Builder
+	if code := simCommand([]string{path, "--set", "b=on", "--format", "json"}, &stdout, &stderr); code != exitSuccess {
+		t.Fatalf("sim returned %d: %s", code, stderr.String())
+	}
+	var result simResult
+	if err := json.Unmarshal([]byte(stdout.String()), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Circuit != "HalfAdder" || result.Inputs["a"] != "0" || result.Inputs["b"] != "1" {
+		t.Errorf("unexpected circuit or inputs in %s", stdout.String())
+	}
+	if len(result.Steps) != 1 || result.Steps[0].Outputs["sum"] != "1" || result.Steps[0].Outputs["carry"] != "0" {
+		t.Errorf("unexpected outputs in %s", stdout.String())
+	}
+}
+
+func TestSimCommandTrace(t *testing.T) {
+	path := testHalfAdderDesign
+	var stdout, stderr strings.Builder
+	args := []string{path, "--set", "a=1", "--trace", "info", "--trace-format", "json", "--trace-component", "sum"}
+	if code := simCommand(args, &stdout, &stderr); code != exitSuccess {
+		t.Fatalf("sim returned %d: %s", code, stderr.String())
+	}
+	expected := `{"step":0,"level":"info","kind":"meter-read","component":"sum","node":"sum-Node","to":"1"}` + "\n"
+	if stderr.String() != expected {
+		t.Errorf("expected trace %q but got %q", expected, stderr.String())
+	}
+}
+
+func TestSimCommandSimulationError(t *testing.T) {
+	// two inputs driving the same wire
+	path := writeTestFile(t, "conflict.circuit", "input a, b\noutput w\nw = a\nw = b\n")
+	var stdout, stderr strings.Builder
+	if code := simCommand([]string{path, "--set", "a=1", "--format", "json"}, &stdout, &stderr); code != exitFailure {
+		t.Errorf("expected exit code %d on a simulation error but got %d", exitFailure, code)
+	}
+	var result simResult
+	if err := json.Unmarshal([]byte(stdout.String()), &result); err != nil || result.Error == "" {
+		t.Errorf("expected the error in the json output but got %s", stdout.String())
+	}
+}
@@ -0,0 +1,276 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+// Input read by interactive subcommands
+var commandInput io.Reader = os.Stdin
+
+const replHelp = `commands:
+  set NAME=VALUE...    sets inputs, applied on the next step
+  step [N]             runs N steps, 1 by default
+  run [N]              steps until the outputs settle, at most N steps
+  show [NAME...]       shows the inputs and outputs, or the named nets
+  trace NAME...        prints the named nets whenever they change
+  untrace NAME...      stops tracing the named nets
+  force NAME VALUE     holds a net at a value whatever drives it
+  release NAME         lets the design drive a forced net again
+  help                 shows this message
+  quit                 exits
+`
+
+// Interactive session poking at a loaded design
+type REPL struct {
+	netlist *sim.Netlist
+	out     io.Writer
+	traced  []string
+	last    map[string]sim.NodeState
+	forced  []string
+}
+
+func NewREPL(netlist *sim.Netlist, out io.Writer) *REPL {
+	return &REPL{
+		netlist: netlist,
+		out:     out,
+		last:    map[string]sim.NodeState{},
+	}
+}
+
+func (r *REPL) node(name string) (*sim.Node, error) {
+	node, ok := r.netlist.Nets[name]
+	if !ok {
+		return nil, fmt.Errorf("no net named %s", name)
+	}
+	return node, nil
+}
+
+func (r *REPL) states(names []string) string {
+	var states []string
+	for _, name := range names {
+		states = append(states, name+"="+sim.StateDigit(r.netlist.Nets[name].State))
+	}
+	return strings.Join(states, " ")
+}
+
+func (r *REPL) step() error {
+	if err := r.netlist.Circuit.Tick(); err != nil {
+		return fmt.Errorf("step %d: %w", r.netlist.Circuit.Steps(), err)
+	}
+	for _, name := range r.traced {
+		state := r.netlist.Nets[name].State
+		if previous := r.last[name]; previous != state {
+			fmt.Fprintf(r.out, "step %d: %s %s -> %s\n",
+				r.netlist.Circuit.Steps()-1, name, sim.StateDigit(previous), sim.StateDigit(state))
+		}
+		r.last[name] = state
+	}
+	return nil
+}
+
+func parseStepCount(args []string, fallback int) (int, error) {
+	if len(args) == 0 {
+		return fallback, nil
+	}
+	steps, err := strconv.Atoi(args[0])
+	if len(args) > 1 || err != nil || steps < 1 {
+		return 0, fmt.Errorf("expected a positive step count")
+	}
+	return steps, nil
+}
+
+// Runs a single REPL command, returning whether the session should end
+func (r *REPL) Execute(line string) (bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	command, args := fields[0], fields[1:]
+	switch command {
+	case "quit", "exit":
+		return true, nil
+	case "help":
+		fmt.Fprint(r.out, replHelp)
+	case "set":
+		if len(args) == 2 && !strings.Contains(args[0], "=") {
+			args = []string{args[0] + "=" + args[1]}
+		}
+		for _, arg := range args {
+			if !strings.Contains(arg, "=") {
+				return false, fmt.Errorf("expected NAME=VALUE but got %q", arg)
+			}
+		}
+		return false, setInputs(r.netlist, args)
+	case "step":
+		steps, err := parseStepCount(args, 1)
+		if err != nil {
+			return false, err
+		}
+		for range steps {
+			if err := r.step(); err != nil {
+				return false, err
+			}
+		}
+		fmt.Fprintf(r.out, "step %d: %s\n", r.netlist.Circuit.Steps()-1, r.states(r.netlist.Outputs))
+	case "run":
+		steps, err := parseStepCount(args, 100)
+		if err != nil {
+			return false, err
+		}
+		watched := append(append([]string{}, r.netlist.Outputs...), r.traced...)
+		previous := ""
+		for i := range steps {
+			if err := r.step(); err != nil {
+				return false, err
+			}
+			current := r.states(watched)
+			if i > 0 && current == previous {
+				fmt.Fprintf(r.out, "settled after %d steps: %s\n", i+1, r.states(r.netlist.Outputs))
+				return false, nil
+			}
+			previous = current
+		}
+		fmt.Fprintf(r.out, "not settled after %d steps: %s\n", steps, r.states(r.netlist.Outputs))
+	case "show":
+		if len(args) == 0 {
+			fmt.Fprintf(r.out, "step %d\n", r.netlist.Circuit.Steps())
+			fmt.Fprintf(r.out, "inputs: %s\n", r.states(r.netlist.Inputs))
+			fmt.Fprintf(r.out, "outputs: %s\n", r.states(r.netlist.Outputs))
+			if len(r.forced) > 0 {
+				fmt.Fprintf(r.out, "forced: %s\n", r.states(r.forced))
+			}
+			return false, nil
+		}
+		for _, name := range args {
+			if _, err := r.node(name); err != nil {
+				return false, err
+			}
+		}
+		fmt.Fprintln(r.out, r.states(args))
+	case "trace":
+		for _, name := range args {
+			node, err := r.node(name)
+			if err != nil {
+				return false, err
+			}
+			if !slices.Contains(r.traced, name) {
+				r.traced = append(r.traced, name)
+				r.last[name] = node.State
+			}
+		}
+	case "untrace":
+		for _, name := range args {
+			if !slices.Contains(r.traced, name) {
+				return false, fmt.Errorf("%s is not traced", name)
+			}
+			r.traced = slices.DeleteFunc(r.traced, func(traced string) bool { return traced == name })
+		}
+	case "force":
+		if len(args) != 2 {
+			return false, fmt.Errorf("usage: force NAME VALUE")
+		}
+		state, err := parseInputValue(args[1])
+		if err != nil {
+			return false, err
+		}
+		return false, r.force(args[0], state)
+	case "release":
+		if len(args) != 1 {
+			return false, fmt.Errorf("usage: release NAME")
+		}
+		return false, r.release(args[0])
+	default:
+		return false, fmt.Errorf("unknown command %s, try help", command)
+	}
+	return false, nil
+}
+
+// Holds the net at state whatever drives it
+func (r *REPL) force(name string, state sim.NodeState) error {
+	node, err := r.node(name)
+	if err != nil {
+		return err
+	}
+	node.Force(state)
+	if !slices.Contains(r.forced, name) {
+		r.forced = append(r.forced, name)
+	}
+	return nil
+}
+
+func (r *REPL) release(name string) error {
+	if !slices.Contains(r.forced, name) {
+		return fmt.Errorf("%s is not forced", name)
+	}
+	r.netlist.Nets[name].Release()
+	r.forced = slices.DeleteFunc(r.forced, func(forced string) bool { return forced == name })
+	return nil
+}
+
+// Reads commands from in until it ends or a quit command, reporting errors
+// without ending the session
+func (r *REPL) Run(in io.Reader, prompt bool) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		if prompt {
+			fmt.Fprint(r.out, "> ")
+		}
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		quit, err := r.Execute(scanner.Text())
+		if err != nil {
+			fmt.Fprintln(r.out, "error:", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// copooter repl FILE [--quiet]
+func replCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	quiet := flags.Bool("quiet", false, "does not print a prompt, for scripted sessions")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: copooter repl FILE [--quiet]")
+		flags.PrintDefaults()
+	}
+	positional, err := parseInterspersed(flags, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitSuccess
+	}
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	netlist, err := sim.LoadDesign(positional[0])
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitFailure
+	}
+	if !*quiet {
+		fmt.Fprintf(stdout, "loaded %s with inputs %s and outputs %s, type help for commands\n",
+			netlist.Name, strings.Join(netlist.Inputs, " "), strings.Join(netlist.Outputs, " "))
+	}
+	if err := NewREPL(netlist, stdout).Run(commandInput, !*quiet); err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitFailure
+	}
+	return exitSuccess
+}
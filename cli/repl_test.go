@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+func TestREPL(t *testing.T) {
+	netlist, err := sim.LoadDesign(testHalfAdderDesign)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := `
+set a=1 b=1
+step
+trace sum
+set b 0
+step
+show
+force sum 0
+step
+show
+release sum
+step
+force a 0
+run
+release a
+show a
+frobnicate
+show nope
+quit
+step
+`
+	expected := "step 0: sum=0 carry=1\n" +
+		"step 1: sum 0 -> 1\n" +
+		"step 1: sum=1 carry=0\n" +
+		"step 2\ninputs: a=1 b=0\noutputs: sum=1 carry=0\n" +
+		"step 2: sum 1 -> 0\n" +
+		"step 2: sum=0 carry=0\n" +
+		"step 3\ninputs: a=1 b=0\noutputs: sum=0 carry=0\nforced: sum=0\n" +
+		"step 3: sum 0 -> 1\n" +
+		"step 3: sum=1 carry=0\n" +
+		"step 4: sum 1 -> 0\n" +
+		"settled after 2 steps: sum=0 carry=0\n" +
+		"a=0\n" +
+		"error: unknown command frobnicate, try help\n" +
+		"error: no net named nope\n"
+
+	var out strings.Builder
+	if err := NewREPL(netlist, &out).Run(strings.NewReader(session), false); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, out.String())
+	}
+}
+
+func TestREPLCommand(t *testing.T) {
+	path := testHalfAdderDesign
+	previous := commandInput
+	defer func() { commandInput = previous }()
+	commandInput = strings.NewReader("set a=1\nstep\n")
+
+	var stdout, stderr strings.Builder
+	if code := replCommand([]string{"--quiet", path}, &stdout, &stderr); code != exitSuccess {
+		t.Fatalf("repl returned %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "step 0: sum=1 carry=0\n" {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/murilo-toddy/copooter/server"
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+// copooter serve FILE [--addr HOST:PORT]
+func serveCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", "localhost:8080", "`address` the server listens on")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: copooter serve FILE [--addr HOST:PORT]")
+		flags.PrintDefaults()
+	}
+	positional, err := parseInterspersed(flags, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitSuccess
+	}
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	netlist, err := sim.LoadDesign(positional[0])
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitFailure
+	}
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitFailure
+	}
+	fmt.Fprintf(stdout, "serving %s on http://%s\n", netlist.Name, listener.Addr())
+	if err := http.Serve(listener, server.NewServer(netlist)); err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitFailure
+	}
+	return exitSuccess
+}
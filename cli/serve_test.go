@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServeCommandErrors(t *testing.T) {
+	tt := []struct {
+		args         []string
+		expectedCode int
+	}{
+		{[]string{}, exitUsage},
+		{[]string{testHalfAdderDesign, testHalfAdderDesign}, exitUsage},
+		{[]string{filepath.Join(t.TempDir(), "missing.circuit")}, exitFailure},
+		{[]string{testHalfAdderDesign, "--addr", "invalid address"}, exitFailure},
+	}
+	for _, tc := range tt {
+		var stdout, stderr strings.Builder
+		if code := serveCommand(tc.args, &stdout, &stderr); code != tc.expectedCode {
+			t.Errorf("serve %v returned %d instead of %d, stderr: %s", tc.args, code, tc.expectedCode, stderr.String())
+		}
+	}
+}
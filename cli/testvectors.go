@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+// copooter test FILE.tv... [--design FILE]
+func testVectorsCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	design := flags.String("design", "", "design under test, overrides the design line of the vector files")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: copooter test FILE.tv... [--design FILE]")
+		flags.PrintDefaults()
+	}
+	paths, err := parseInterspersed(flags, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitSuccess
+	}
+	if err != nil {
+		return exitUsage
+	}
+	if len(paths) == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	code := exitSuccess
+	for _, path := range paths {
+		mismatches, err := sim.RunTestVectorFile(path, *design)
+		for _, mismatch := range mismatches {
+			fmt.Fprintf(stdout, "%s:%s\n", path, strings.TrimPrefix(mismatch.String(), "line "))
+		}
+		switch {
+		case err != nil:
+			fmt.Fprintln(stderr, "error:", err)
+			code = exitFailure
+		case len(mismatches) > 0:
+			fmt.Fprintf(stdout, "FAIL %s: %d mismatched rows\n", path, len(mismatches))
+			code = exitFailure
+		default:
+			fmt.Fprintf(stdout, "ok   %s\n", path)
+		}
+	}
+	return code
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVectorsCommand(t *testing.T) {
+	failing := writeTestFile(t, "failing.tv", "a b | sum carry\n1 1 | 1 1\n")
+	tt := []struct {
+		args           []string
+		expectedCode   int
+		expectedOutput string
+	}{
+		{[]string{filepath.Join("..", "sim", "testdata", "half_adder.tv")}, exitSuccess, "ok   ../sim/testdata/half_adder.tv\n"},
+		{[]string{failing, "--design", filepath.Join("..", "sim", "testdata", "half_adder.circuit")}, exitFailure,
+			failing + ":2: inputs a=1 b=1 expected sum=1 carry=1 but got sum=0 carry=1\nFAIL " + failing + ": 1 mismatched rows\n"},
+		{[]string{failing}, exitFailure, ""},
+		{nil, exitUsage, ""},
+	}
+	for _, tc := range tt {
+		var stdout, stderr strings.Builder
+		code := testVectorsCommand(tc.args, &stdout, &stderr)
+		if code != tc.expectedCode || stdout.String() != tc.expectedOutput {
+			t.Errorf("test %v returned %d with output %q instead of %d with output %q, stderr: %s",
+				tc.args, code, stdout.String(), tc.expectedCode, tc.expectedOutput, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+// copooter truthtable FILE [--format markdown|csv|go] [--samples N] [--seed S]
+func truthTableCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("truthtable", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "markdown", "output format, markdown, csv or go")
+	samples := flags.Int("samples", 4096, "random input combinations simulated when there are more combinations than this, 0 enumerates all of them up to 20 inputs")
+	seed := flags.Int64("seed", 1, "seed of the sampled input combinations")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: copooter truthtable FILE [--format markdown|csv|go] [--samples N] [--seed S]")
+		flags.PrintDefaults()
+	}
+	positional, err := parseInterspersed(flags, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitSuccess
+	}
+	if err != nil {
+		return exitUsage
+	}
+	writers := map[string]func(*sim.TruthTable, io.Writer) error{
+		"markdown": (*sim.TruthTable).WriteMarkdown,
+		"csv":      (*sim.TruthTable).WriteCSV,
+		"go":       (*sim.TruthTable).WriteGoTestTable,
+	}
+	write, ok := writers[*format]
+	if len(positional) != 1 || !ok {
+		flags.Usage()
+		return exitUsage
+	}
+
+	netlist, err := sim.LoadDesign(positional[0])
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitFailure
+	}
+	table, err := sim.GenerateTruthTable(netlist, *samples, rand.New(rand.NewSource(*seed)))
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitFailure
+	}
+	if table.Sampled {
+		fmt.Fprintf(stderr, "%d inputs, showing %d sampled combinations\n", len(table.Inputs), len(table.Rows))
+	}
+	if err := write(table, stdout); err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitFailure
+	}
+	return exitSuccess
+}
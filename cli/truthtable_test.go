@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruthTableCommand(t *testing.T) {
+	path := testHalfAdderDesign
+	var stdout, stderr strings.Builder
+	if code := truthTableCommand([]string{"--format", "csv", path}, &stdout, &stderr); code != exitSuccess {
+		t.Fatalf("truthtable returned %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "a,b,sum,carry\n") {
+		t.Errorf("unexpected csv output %q", stdout.String())
+	}
+	if code := truthTableCommand([]string{"--format", "html", path}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("expected exit code %d for an unknown format but got %d", exitUsage, code)
+	}
+}
@@ -0,0 +1,30 @@
+// Headless copooter, running the simulator subcommands without linking the
+// raylib GUI, so it builds with CGO_ENABLED=0 on machines with no display
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/murilo-toddy/copooter/cli"
+)
+
+func main() {
+	var names []string
+	for name := range cli.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "usage: copooter-cli <%s> [flags]\n", strings.Join(names, "|"))
+		os.Exit(2)
+	}
+	command, ok := cli.Commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q, expected one of %s\n", os.Args[1], strings.Join(names, ", "))
+		os.Exit(2)
+	}
+	os.Exit(command(os.Args[2:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/murilo-toddy/copooter/render"
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+var (
+	debuggerPanelWidth = int32(520)
+	debuggerFontSize   = int32(16)
+	// Components listed in the pending and deferred queues at most
+	debuggerQueueLength = 6
+	actingColor         = rl.Orange
+	changedNodeColor    = rl.Green
+	breakpointColor     = rl.Red
+)
+
+// Debugger going through the steps of the placed circuit one component Act at
+// a time, along with the breakpoints it stops at
+type DebugSession struct {
+	debugger *sim.Debugger
+	// last step taken, nil before the first one
+	step *sim.DebugStep
+	// history circuit revision the circuit was built at, it is built again
+	// after edits other than toggling inputs
+	revision    int
+	breakpoints []sim.Breakpoint
+}
+
+func (d *DebugSession) Active() bool {
+	return d.debugger != nil
+}
+
+// Starts debugging the next step of the circuit, building it again when the
+// schematic changed. The live simulation is paused meanwhile
+func startDebugging(s *DrawingState) {
+	d := &s.debug
+	s.live.playing = false
+	circuit := sim.NewCircuit(s.components, liveMaxDefers, false)
+	if d.debugger != nil && d.revision == s.history.CircuitRevision() {
+		circuit = d.debugger.Circuit()
+	}
+	d.revision = s.history.CircuitRevision()
+	d.step = nil
+	d.debugger = circuit.Debug(d.breakpoints...)
+}
+
+func stopDebugging(s *DrawingState) {
+	if s.debug.debugger != nil {
+		s.debug.debugger.Circuit().Reset()
+	}
+	s.debug = DebugSession{breakpoints: s.debug.breakpoints}
+}
+
+// Steps once, or until a breakpoint is hit when continuing, starting on the
+// next circuit step once the current one is over
+func stepDebugger(s *DrawingState, continuing bool) {
+	d := &s.debug
+	if !d.Active() || d.debugger.Done() || d.revision != s.history.CircuitRevision() {
+		startDebugging(s)
+	}
+	var step *sim.DebugStep
+	var err error
+	if continuing {
+		step, err = d.debugger.Continue()
+	} else {
+		step, err = d.debugger.Step()
+	}
+	d.step = step
+	if err != nil {
+		fmt.Println("Failed to debug circuit: ", err.Error())
+	}
+}
+
+// Adds the breakpoint, or removes it when it was already set
+func toggleBreakpoint(s *DrawingState, breakpoint sim.Breakpoint) {
+	d := &s.debug
+	if index := slices.Index(d.breakpoints, breakpoint); index >= 0 {
+		d.breakpoints = slices.Delete(d.breakpoints, index, index+1)
+	} else {
+		d.breakpoints = append(d.breakpoints, breakpoint)
+	}
+	if d.Active() {
+		d.debugger.Breakpoints = d.breakpoints
+	}
+}
+
+// F10 steps one component, F5 continues to the next breakpoint and Shift+F5
+// stops debugging. F9 sets a breakpoint on the selected node becoming on, or
+// off with shift, or on the selected component acting
+func checkDebuggerControls(s *DrawingState, pos rl.Vector2) {
+	switch {
+	case isTextButtonClicked(pos, 5):
+		if s.debug.Active() {
+			stopDebugging(s)
+		} else {
+			startDebugging(s)
+		}
+	case rl.IsKeyPressed(rl.KeyF10):
+		stepDebugger(s, false)
+	case rl.IsKeyPressed(rl.KeyF5) && isShiftDown():
+		stopDebugging(s)
+	case rl.IsKeyPressed(rl.KeyF5):
+		stepDebugger(s, true)
+	case rl.IsKeyPressed(rl.KeyF9) && s.state == StateNodeSelected:
+		state := sim.NodeState(sim.On)
+		if isShiftDown() {
+			state = sim.Off
+		}
+		node := (*s.selectedComponent).Nodes()[*s.selectedNode]
+		toggleBreakpoint(s, sim.Breakpoint{Node: node, State: state})
+	case rl.IsKeyPressed(rl.KeyF9) && s.state == StateComponentSelected:
+		toggleBreakpoint(s, sim.Breakpoint{Component: *s.selectedComponent})
+	}
+}
+
+// Marks the breakpoints, the acting component and the nodes it changed, in
+// world coordinates
+func drawDebuggerHighlights(s DrawingState) {
+	for _, breakpoint := range s.debug.breakpoints {
+		if breakpoint.Node != nil {
+			if slices.Contains(s.components, breakpoint.Node.Parent) {
+				x, y := render.TerminalCoordinates(breakpoint.Node)
+				rl.DrawCircleLines(int32(x), int32(y), render.TerminalRadius+3, breakpointColor)
+			}
+			continue
+		}
+		if slices.Contains(s.components, breakpoint.Component) {
+			x, y := breakpoint.Component.GetPosition()
+			rl.DrawCircle(x, y, render.TerminalRadius, breakpointColor)
+		}
+	}
+	if !s.debug.Active() || s.debug.step == nil {
+		return
+	}
+	if acting := s.debug.step.Component; acting != nil {
+		render.DrawComponentOutline(acting, actingColor)
+	}
+	for _, node := range s.debug.step.Changed {
+		if slices.Contains(s.components, node.Parent) {
+			render.DrawTerminal(node.Parent, node, changedNodeColor)
+		}
+	}
+}
+
+func componentNames(components []sim.Component) string {
+	var names []string
+	for i, component := range components {
+		if i == debuggerQueueLength {
+			names = append(names, fmt.Sprintf("and %d more", len(components)-i))
+			break
+		}
+		names = append(names, sim.ComponentName(component))
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+func nodeNames(nodes []*sim.Node) string {
+	var names []string
+	for _, node := range nodes {
+		names = append(names, fmt.Sprintf("%s=%s", sim.NodeName(node), sim.StateDigit(node.State)))
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// Lists what the scheduler did last and what it will consider next
+func drawDebuggerPanel(s DrawingState) {
+	d := s.debug
+	if !d.Active() && len(d.breakpoints) == 0 {
+		return
+	}
+	lines := []string{"F10 step | F5 continue | Shift+F5 stop | F9 breakpoint"}
+	if d.Active() {
+		debugger := d.debugger
+		lines = append(lines, fmt.Sprintf("circuit step %d, scheduler round %d", debugger.Circuit().Steps(), debugger.Round()))
+		switch {
+		case d.step == nil:
+			lines = append(lines, "nothing acted yet")
+		case d.step.Restarted:
+			lines = append(lines, "feedback loop settled elsewhere, step started over")
+		case d.step.Component == nil:
+			lines = append(lines, "step over, meters read")
+		default:
+			lines = append(lines, "acted: "+sim.ComponentName(d.step.Component))
+			if len(d.step.Deferred) > 0 {
+				lines = append(lines, "deferred first: "+componentNames(d.step.Deferred))
+			}
+		}
+		if d.step != nil {
+			lines = append(lines, "changed: "+nodeNames(d.step.Changed))
+		}
+		lines = append(lines,
+			"pending: "+componentNames(debugger.Pending()),
+			"deferred queue: "+componentNames(debugger.Deferred()),
+		)
+	}
+	for _, breakpoint := range d.breakpoints {
+		lines = append(lines, "breakpoint: "+breakpoint.String())
+	}
+
+	lineHeight := debuggerFontSize + actionsOffset/2
+	x := width - actionsOffset - debuggerPanelWidth
+	y := 3*actionsOffset + 2*actionButtonSize
+	rl.DrawRectangle(x, y, debuggerPanelWidth, int32(len(lines))*lineHeight+actionsOffset, rl.NewColor(32, 32, 32, 230))
+	for i, line := range lines {
+		rl.DrawText(line, x+actionsOffset/2, y+actionsOffset/2+int32(i)*lineHeight, debuggerFontSize, rl.White)
+	}
+}
@@ -2,9 +2,10 @@ module github.com/murilo-toddy/copooter
 
 go 1.22.2
 
+require github.com/gen2brain/raylib-go/raylib v0.0.0-20241228120719-d58ffe1a3a73
+
 require (
 	github.com/ebitengine/purego v0.8.1 // indirect
-	github.com/gen2brain/raylib-go/raylib v0.0.0-20241228120719-d58ffe1a3a73 // indirect
 	golang.org/x/exp v0.0.0-20250106191152-7588d65b2ba8 // indirect
 	golang.org/x/sys v0.29.0 // indirect
 )
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/murilo-toddy/copooter/render"
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+var (
+	hierarchyPanelWidth  = int32(320)
+	hierarchyRowHeight   = int32(16)
+	hierarchyFontSize    = int32(12)
+	hierarchyIndentWidth = int32(14)
+	drillDownBoxSpacing  = render.ComponentImageSize
+)
+
+func hierarchyPanelPosition() (int32, int32) {
+	return width - hierarchyPanelWidth, actionsOffset*2 + actionButtonSize
+}
+
+func isInsideHierarchyPanel(pos rl.Vector2) bool {
+	x, y := hierarchyPanelPosition()
+	return isInsideSquare(pos, x, y, hierarchyPanelWidth, height-y)
+}
+
+func checkToggleHierarchyPanel(s *DrawingState) {
+	if rl.IsKeyPressed(rl.KeyH) {
+		s.hierarchyVisible = !s.hierarchyVisible
+	}
+}
+
+// Clicking the marker of a row expands or collapses it, clicking its label
+// opens the drill-down view. Returns whether the click was consumed
+func checkHierarchyPanelClicked(s *DrawingState, pos rl.Vector2) bool {
+	if !s.hierarchyVisible || !isInsideHierarchyPanel(pos) {
+		return false
+	}
+	if !rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
+		return true
+	}
+	x, y := hierarchyPanelPosition()
+	rows := sim.VisibleHierarchyRows(sim.BuildHierarchy(s.components), s.hierarchyExpanded, 0)
+	index := (int32(pos.Y) - y) / hierarchyRowHeight
+	if index < 0 || index >= int32(len(rows)) {
+		return true
+	}
+	row := rows[index]
+	if len(row.Entry.Children) == 0 {
+		return true
+	}
+	if int32(pos.X) < x+(row.Depth+1)*hierarchyIndentWidth {
+		s.hierarchyExpanded[row.Entry.Component] = !s.hierarchyExpanded[row.Entry.Component]
+	} else {
+		s.drillDown = []sim.Component{row.Entry.Component}
+		s.state = StateDrillDown
+	}
+	return true
+}
+
+func drawHierarchyPanel(s DrawingState) {
+	if !s.hierarchyVisible {
+		return
+	}
+	x, y := hierarchyPanelPosition()
+	rl.DrawRectangle(x, y, hierarchyPanelWidth, height-y, rl.NewColor(32, 32, 32, 230))
+	for i, row := range sim.VisibleHierarchyRows(sim.BuildHierarchy(s.components), s.hierarchyExpanded, 0) {
+		rowX := x + row.Depth*hierarchyIndentWidth + actionsOffset/2
+		rowY := y + int32(i)*hierarchyRowHeight + (hierarchyRowHeight-hierarchyFontSize)/2
+		marker := " "
+		if len(row.Entry.Children) > 0 {
+			marker = "+"
+			if s.hierarchyExpanded[row.Entry.Component] {
+				marker = "-"
+			}
+		}
+		rl.DrawText(marker, rowX, rowY, hierarchyFontSize, rl.Gray)
+		label := fmt.Sprintf("%s (%d T)", row.Entry.Label, row.Entry.Transistors)
+		rl.DrawText(label, rowX+hierarchyIndentWidth, rowY, hierarchyFontSize, rl.White)
+	}
+}
+
+// Position of every subcomponent on the drill-down canvas, laid out on a
+// grid in the order they were declared
+func drillDownLayout(subcomponents []sim.Component) []sim.Position {
+	columns := int32(math.Ceil(math.Sqrt(float64(len(subcomponents)))))
+	cell := render.ComponentImageSize + drillDownBoxSpacing
+	originX := toolkitSidebarSize + cell
+	originY := cell
+	positions := make([]sim.Position, len(subcomponents))
+	for i := range subcomponents {
+		positions[i] = sim.Position{
+			X: originX + int32(i)%columns*cell,
+			Y: originY + int32(i)/columns*cell,
+		}
+	}
+	return positions
+}
+
+// Offsets of the nodes of c relative to its box on the drill-down canvas
+func drillDownPortOffsets(c sim.Component) map[*sim.Node]rl.Vector2 {
+	offsets := map[*sim.Node]rl.Vector2{}
+	switch c := c.(type) {
+	case *sim.Transistor:
+		offsets[c.Source] = rl.Vector2{X: 0.6, Y: 0.05}
+		offsets[c.Gate] = rl.Vector2{X: 0.05, Y: 0.5}
+		offsets[c.Drain] = rl.Vector2{X: 0.6, Y: 0.95}
+	case *sim.Resistor:
+		offsets[c.Node1] = rl.Vector2{X: 0.0, Y: 0.5}
+		offsets[c.Node2] = rl.Vector2{X: 1.0, Y: 0.5}
+	default:
+		inputs, outputs := sim.Ports(c)
+		for i, input := range inputs {
+			offsets[input] = rl.Vector2{X: 0.0, Y: float32(i+1) / float32(len(inputs)+1)}
+		}
+		for i, output := range outputs {
+			offsets[output] = rl.Vector2{X: 1.0, Y: float32(i+1) / float32(len(outputs)+1)}
+		}
+	}
+	return offsets
+}
+
+func checkDrillDownActions(s *DrawingState, pos rl.Vector2) {
+	if rl.IsKeyPressed(rl.KeyQ) {
+		s.drillDown = nil
+		s.state = StateIdle
+		return
+	}
+	if rl.IsKeyPressed(rl.KeyBackspace) {
+		s.drillDown = s.drillDown[:len(s.drillDown)-1]
+		if len(s.drillDown) == 0 {
+			s.state = StateIdle
+		}
+		return
+	}
+	if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
+		subcomponents := sim.Subcomponents(s.drillDown[len(s.drillDown)-1])
+		for i, position := range drillDownLayout(subcomponents) {
+			if isInsideSquare(pos, position.X, position.Y, render.ComponentImageSize, render.ComponentImageSize) &&
+				len(sim.Subcomponents(subcomponents[i])) > 0 {
+				s.drillDown = append(s.drillDown, subcomponents[i])
+				return
+			}
+		}
+	}
+}
+
+// Draws the internals of the innermost drilled component, nodes are coloured
+// with their current state so the view follows the simulation
+func drawDrillDown(s DrawingState) {
+	rl.DrawRectangle(toolkitSidebarSize, 0, width-toolkitSidebarSize, height, rl.NewColor(12, 12, 12, 245))
+
+	var breadcrumb string
+	for i, component := range s.drillDown {
+		if i > 0 {
+			breadcrumb += " > "
+		}
+		breadcrumb += sim.ComponentLabel(component)
+	}
+	rl.DrawText(breadcrumb+"   (backspace: up, q: close)", toolkitSidebarSize+actionsOffset, actionsOffset, toolkitComponentNameFontSize, rl.White)
+
+	current := s.drillDown[len(s.drillDown)-1]
+	subcomponents := sim.Subcomponents(current)
+	coordinates := map[*sim.Node]rl.Vector2{}
+
+	// ports of the drilled component are pinned to the canvas edges
+	inputs, outputs := sim.Ports(current)
+	for i, input := range inputs {
+		coordinates[input] = rl.Vector2{
+			X: float32(toolkitSidebarSize + actionsOffset*2),
+			Y: float32(height) * float32(i+1) / float32(len(inputs)+1),
+		}
+	}
+	for i, output := range outputs {
+		coordinates[output] = rl.Vector2{
+			X: float32(width - actionsOffset*2),
+			Y: float32(height) * float32(i+1) / float32(len(outputs)+1),
+		}
+	}
+
+	positions := drillDownLayout(subcomponents)
+	for i, subcomponent := range subcomponents {
+		x, y := positions[i].Unpack()
+		color := rl.White
+		if len(sim.Subcomponents(subcomponent)) > 0 {
+			color = rl.SkyBlue
+		}
+		rl.DrawRectangleLines(x, y, render.ComponentImageSize, render.ComponentImageSize, color)
+		rl.DrawText(sim.ComponentLabel(subcomponent), x, y+render.ComponentImageSize, render.ComponentFontSize, color)
+		if transistors := sim.CountTransistors(subcomponent); transistors > 0 {
+			rl.DrawText(fmt.Sprintf("%d T", transistors), x+render.ComponentFontSize, y+render.ComponentFontSize, render.ComponentFontSize, rl.Gray)
+		}
+		for node, offset := range drillDownPortOffsets(subcomponent) {
+			coordinates[node] = rl.Vector2{
+				X: float32(x) + float32(render.ComponentImageSize)*offset.X,
+				Y: float32(y) + float32(render.ComponentImageSize)*offset.Y,
+			}
+		}
+	}
+
+	// connect every drawn node to the other drawn nodes of its net
+	drawn := map[*sim.Node]bool{}
+	for node, from := range coordinates {
+		drawn[node] = true
+		for other := range sim.ConnectedNodes(node) {
+			to, ok := coordinates[other]
+			if !ok || drawn[other] {
+				continue
+			}
+			rl.DrawLineEx(from, to, 2, render.NodeStateColor(node))
+		}
+	}
+	for node, center := range coordinates {
+		rl.DrawCircleV(center, render.TerminalRadius, render.NodeStateColor(node))
+		rl.DrawText(node.State.String(), int32(center.X)+int32(render.TerminalRadius), int32(center.Y), render.ComponentFontSize, rl.Gray)
+	}
+}
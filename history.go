@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+var (
+	statusFontSize = int32(16)
+	// Edits kept for undo, the oldest are dropped past it
+	maxHistoryDepth = 500
+)
+
+// Reversible edit of the schematic
+type EditCommand interface {
+	Do(s *DrawingState)
+	Undo(s *DrawingState)
+	Description() string
+}
+
+// Edits done on the schematic, undone and redone in order
+type History struct {
+	undo []EditCommand
+	redo []EditCommand
+	// changes whenever the schematic is edited
+	revision int
+	// changes whenever the schematic is edited beyond the states of its
+	// inputs, which circuits already built pick up on their next step
+	circuitRevision int
+}
+
+// Edit only changing the states driven by inputs
+type inputEdit interface {
+	changesInputsOnly()
+}
+
+func (h *History) edited(command EditCommand) {
+	h.revision++
+	if _, ok := command.(inputEdit); !ok {
+		h.circuitRevision++
+	}
+}
+
+// Applies the command, discarding the edits undone before it
+func (h *History) Execute(s *DrawingState, command EditCommand) {
+	command.Do(s)
+	h.undo = append(h.undo, command)
+	if len(h.undo) > maxHistoryDepth {
+		h.undo = h.undo[len(h.undo)-maxHistoryDepth:]
+	}
+	h.redo = nil
+	h.edited(command)
+}
+
+func (h *History) Undo(s *DrawingState) bool {
+	if len(h.undo) == 0 {
+		return false
+	}
+	command := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	command.Undo(s)
+	h.redo = append(h.redo, command)
+	h.edited(command)
+	return true
+}
+
+func (h *History) Redo(s *DrawingState) bool {
+	if len(h.redo) == 0 {
+		return false
+	}
+	command := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	command.Do(s)
+	h.undo = append(h.undo, command)
+	h.edited(command)
+	return true
+}
+
+func (h *History) Clear() {
+	h.undo = nil
+	h.redo = nil
+	h.revision++
+	h.circuitRevision++
+}
+
+// Number of edits applied, undone or redone, compared to tell whether the
+// schematic changed
+func (h *History) Revision() int {
+	return h.revision
+}
+
+// Number of edits applied, undone or redone that a circuit built before them
+// does not pick up
+func (h *History) CircuitRevision() int {
+	return h.circuitRevision
+}
+
+func componentIndex(s *DrawingState, c sim.Component) int {
+	return slices.Index(s.components, c)
+}
+
+func insertComponent(s *DrawingState, index int, c sim.Component) {
+	s.components = slices.Insert(s.components, index, c)
+}
+
+func removeComponent(s *DrawingState, c sim.Component) {
+	if index := componentIndex(s, c); index >= 0 {
+		s.components = slices.Delete(s.components, index, index+1)
+	}
+}
+
+type placeComponentCommand struct {
+	component sim.Component
+}
+
+func (c *placeComponentCommand) Do(s *DrawingState) {
+	s.components = append(s.components, c.component)
+}
+
+func (c *placeComponentCommand) Undo(s *DrawingState) {
+	removeComponent(s, c.component)
+}
+
+func (c *placeComponentCommand) Description() string {
+	return "place " + c.component.GetID().Name
+}
+
+// Deletes the component along with its wires, connections to the internals
+// of blocks have no wire and are kept
+type deleteComponentCommand struct {
+	component sim.Component
+	index     int
+	wires     []*sim.Wire
+}
+
+func (c *deleteComponentCommand) Do(s *DrawingState) {
+	c.index = componentIndex(s, c.component)
+	c.wires = nil
+	for _, wire := range slices.Clone(s.wires) {
+		if slices.ContainsFunc(c.component.Nodes(), wire.Touches) {
+			c.wires = append(c.wires, wire)
+			wire.Disconnect()
+			removeWire(s, wire)
+		}
+	}
+	removeComponent(s, c.component)
+}
+
+func (c *deleteComponentCommand) Undo(s *DrawingState) {
+	insertComponent(s, c.index, c.component)
+	for _, wire := range c.wires {
+		wire.Connect()
+		s.wires = append(s.wires, wire)
+	}
+}
+
+func (c *deleteComponentCommand) Description() string {
+	return "delete " + c.component.GetID().Name
+}
+
+// Edits applied and undone together, such as the ones on every selected
+// component
+type groupCommand struct {
+	commands    []EditCommand
+	description string
+}
+
+func (c *groupCommand) Do(s *DrawingState) {
+	for _, command := range c.commands {
+		command.Do(s)
+	}
+}
+
+func (c *groupCommand) Undo(s *DrawingState) {
+	for i := len(c.commands) - 1; i >= 0; i-- {
+		c.commands[i].Undo(s)
+	}
+}
+
+func (c *groupCommand) Description() string {
+	return c.description
+}
+
+// Executes the commands as a single edit, described after verb and the
+// number of components when there are several
+func executeGroup(s *DrawingState, verb string, commands []EditCommand) {
+	switch len(commands) {
+	case 0:
+		return
+	case 1:
+		s.history.Execute(s, commands[0])
+	default:
+		s.history.Execute(s, &groupCommand{commands, fmt.Sprintf("%s %d components", verb, len(commands))})
+	}
+}
+
+type moveComponentsCommand struct {
+	components []sim.Movable
+	from, to   []sim.Position
+}
+
+func (c *moveComponentsCommand) Do(s *DrawingState) {
+	for i, component := range c.components {
+		component.SetPosition(c.to[i])
+	}
+}
+
+func (c *moveComponentsCommand) Undo(s *DrawingState) {
+	for i, component := range c.components {
+		component.SetPosition(c.from[i])
+	}
+}
+
+func (c *moveComponentsCommand) Description() string {
+	if len(c.components) == 1 {
+		return "move " + c.components[0].GetID().Name
+	}
+	return fmt.Sprintf("move %d components", len(c.components))
+}
+
+// Quarter turn clockwise, undone by turning three more times
+type rotateComponentCommand struct {
+	component sim.Movable
+}
+
+func (c *rotateComponentCommand) Do(s *DrawingState) {
+	sim.Rotate(c.component)
+}
+
+func (c *rotateComponentCommand) Undo(s *DrawingState) {
+	for range 3 {
+		sim.Rotate(c.component)
+	}
+}
+
+func (c *rotateComponentCommand) Description() string {
+	return "rotate " + c.component.GetID().Name
+}
+
+type flipComponentCommand struct {
+	component sim.Movable
+}
+
+func (c *flipComponentCommand) Do(s *DrawingState) {
+	sim.Flip(c.component)
+}
+
+func (c *flipComponentCommand) Undo(s *DrawingState) {
+	sim.Flip(c.component)
+}
+
+func (c *flipComponentCommand) Description() string {
+	return "flip " + c.component.GetID().Name
+}
+
+func removeWire(s *DrawingState, wire *sim.Wire) {
+	if index := slices.Index(s.wires, wire); index >= 0 {
+		s.wires = slices.Delete(s.wires, index, index+1)
+	}
+}
+
+func wireName(wire *sim.Wire) string {
+	return fmt.Sprintf("%s to %s", wire.From.Parent.GetID().Name, wire.To.Parent.GetID().Name)
+}
+
+type addWireCommand struct {
+	wire *sim.Wire
+}
+
+func (c *addWireCommand) Do(s *DrawingState) {
+	c.wire.Connect()
+	s.wires = append(s.wires, c.wire)
+}
+
+func (c *addWireCommand) Undo(s *DrawingState) {
+	c.wire.Disconnect()
+	removeWire(s, c.wire)
+}
+
+func (c *addWireCommand) Description() string {
+	return "connect " + wireName(c.wire)
+}
+
+type deleteWireCommand struct {
+	wire *sim.Wire
+}
+
+func (c *deleteWireCommand) Do(s *DrawingState) {
+	c.wire.Disconnect()
+	removeWire(s, c.wire)
+}
+
+func (c *deleteWireCommand) Undo(s *DrawingState) {
+	c.wire.Connect()
+	s.wires = append(s.wires, c.wire)
+}
+
+func (c *deleteWireCommand) Description() string {
+	return "delete wire " + wireName(c.wire)
+}
+
+type setInputStateCommand struct {
+	terminal *sim.Terminal
+	from, to sim.NodeState
+}
+
+func (c *setInputStateCommand) Do(s *DrawingState) {
+	c.terminal.SetState(c.to)
+}
+
+func (c *setInputStateCommand) Undo(s *DrawingState) {
+	c.terminal.SetState(c.from)
+}
+
+func (c *setInputStateCommand) changesInputsOnly() {}
+
+func (c *setInputStateCommand) Description() string {
+	return fmt.Sprintf("set %s to %s", c.terminal.GetID().Name, c.to)
+}
+
+type setInstanceLevelCommand struct {
+	instance *sim.BlockInstance
+	from, to sim.AbstractionLevel
+}
+
+func (c *setInstanceLevelCommand) Do(s *DrawingState) {
+	c.instance.SetLevel(c.to)
+}
+
+func (c *setInstanceLevelCommand) Undo(s *DrawingState) {
+	c.instance.SetLevel(c.from)
+}
+
+func (c *setInstanceLevelCommand) Description() string {
+	return fmt.Sprintf("set %s to %s", c.instance.Name, c.to)
+}
+
+// Ctrl+Z undoes the last edit, Ctrl+Y or Ctrl+Shift+Z redoes it. The
+// selection is dropped since the selected component may be gone
+func checkUndoRedo(s *DrawingState) {
+	if !isControlDown() {
+		return
+	}
+	shiftDown := rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift)
+	changed := false
+	switch {
+	case rl.IsKeyPressed(rl.KeyZ) && !shiftDown:
+		changed = s.history.Undo(s)
+	case rl.IsKeyPressed(rl.KeyY), rl.IsKeyPressed(rl.KeyZ) && shiftDown:
+		changed = s.history.Redo(s)
+	}
+	if changed {
+		clearSelection(s)
+	}
+}
+
+func drawStatusBar(s DrawingState) {
+	status := fmt.Sprintf("undo %d | redo %d", len(s.history.undo), len(s.history.redo))
+	if len(s.history.undo) > 0 {
+		status += " | last: " + s.history.undo[len(s.history.undo)-1].Description()
+	}
+	rl.DrawText(status, toolkitSidebarSize+actionsOffset, height-actionsOffset-statusFontSize, statusFontSize, rl.Gray)
+}
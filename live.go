@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+var (
+	// Rates the live simulation can tick at, chosen with the - and + buttons
+	liveTickRates = []int{1, 2, 5, 10, 20, 50, 100}
+	// Ticks run in a single frame at most, so slow frames do not pile up
+	maxTicksPerFrame = 10
+	liveMaxDefers    = 50
+)
+
+// Simulation kept running while editing the schematic, built again whenever
+// the schematic changes, stepped once when an input is toggled and ticked at
+// a steady rate while playing
+type LiveSimulation struct {
+	enabled bool
+	playing bool
+	// index of the rate in liveTickRates
+	rate    int
+	circuit *sim.Circuit
+	// history circuit revision the circuit was built at, toggling inputs
+	// keeps the circuit and the state held by its components
+	revision int
+	// history revision last simulated
+	edits int
+	// seconds elapsed since the last tick
+	elapsed float32
+	ticks   uint64
+	err     error
+}
+
+func NewLiveSimulation() LiveSimulation {
+	return LiveSimulation{rate: 3}
+}
+
+func (l *LiveSimulation) TicksPerSecond() int {
+	return liveTickRates[l.rate]
+}
+
+// Runs a single step, pausing on failures so they are reported once
+func (l *LiveSimulation) tick() {
+	if l.err = l.circuit.Tick(); l.err != nil {
+		fmt.Println("Failed to run circuit: ", l.err.Error())
+		l.playing = false
+		return
+	}
+	l.ticks++
+}
+
+// Builds the circuit from the placed components and simulates it. Only this
+// first step reports meter readings, the ones played after it are silent
+func (l *LiveSimulation) rebuild(s *DrawingState) {
+	l.circuit = sim.NewCircuit(s.components, liveMaxDefers, false)
+	l.revision = s.history.CircuitRevision()
+	l.edits = s.history.Revision()
+	l.elapsed = 0
+	l.tick()
+	l.circuit.SetTracer(sim.DefaultTracer.WithLevel(sim.TraceOff))
+}
+
+func startLiveSimulation(s *DrawingState, playing bool) {
+	s.live.enabled = true
+	s.live.playing = playing
+	s.live.ticks = 0
+	s.live.rebuild(s)
+}
+
+// Stops simulating, leaving every node undefined again
+func stopLiveSimulation(s *DrawingState) {
+	if s.live.circuit != nil {
+		s.live.circuit.Reset()
+	}
+	s.live = LiveSimulation{rate: s.live.rate}
+}
+
+// Simulates again after edits and ticks at the chosen rate while playing
+func updateLiveSimulation(s *DrawingState) {
+	l := &s.live
+	// the debugger drives the nodes while stepping
+	if !l.enabled || s.debug.Active() {
+		return
+	}
+	if l.revision != s.history.CircuitRevision() {
+		l.rebuild(s)
+	} else if l.edits != s.history.Revision() {
+		l.edits = s.history.Revision()
+		l.tick()
+	}
+	if !l.playing {
+		return
+	}
+	period := 1 / float32(l.TicksPerSecond())
+	l.elapsed += rl.GetFrameTime()
+	for range maxTicksPerFrame {
+		if l.elapsed < period || !l.playing {
+			break
+		}
+		l.elapsed -= period
+		l.tick()
+	}
+	l.elapsed = min(l.elapsed, period)
+}
+
+// Position of the live simulation buttons, laid out right to left below the
+// play button
+func liveButtonPosition(index int32) (int32, int32) {
+	return width - actionsOffset - actionButtonSize - index*(actionButtonSize+actionsOffset), 2*actionsOffset + actionButtonSize
+}
+
+func isLiveButtonClicked(pos rl.Vector2, index int32) bool {
+	x, y := liveButtonPosition(index)
+	return rl.IsMouseButtonReleased(rl.MouseButtonLeft) && isInsideSquare(pos, x, y, actionButtonSize, actionButtonSize)
+}
+
+func drawLiveButton(index int32, label string, color rl.Color) {
+	x, y := liveButtonPosition(index)
+	rl.DrawRectangle(x, y, actionButtonSize, actionButtonSize, color)
+	textWidth := rl.MeasureText(label, actionButtonFontSize)
+	rl.DrawText(label, x+(actionButtonSize-textWidth)/2, y+(actionButtonSize-actionButtonFontSize)/2, actionButtonFontSize, rl.White)
+}
+
+// The play button plays and pauses the live simulation, the buttons below it
+// step a paused simulation, stop it and change its rate
+func checkLiveControls(s *DrawingState, pos rl.Vector2) {
+	switch {
+	case isPlayButtonClicked(pos):
+		if s.live.enabled {
+			s.live.playing = !s.live.playing
+			s.live.elapsed = 0
+		} else {
+			startLiveSimulation(s, true)
+		}
+	case isLiveButtonClicked(pos, 0):
+		if !s.live.enabled {
+			startLiveSimulation(s, false)
+		} else if !s.live.playing {
+			s.live.tick()
+		}
+	case isLiveButtonClicked(pos, 1) && s.live.enabled:
+		stopLiveSimulation(s)
+	case isLiveButtonClicked(pos, 2):
+		s.live.rate = min(s.live.rate+1, len(liveTickRates)-1)
+	case isLiveButtonClicked(pos, 3):
+		s.live.rate = max(s.live.rate-1, 0)
+	}
+}
+
+func drawLiveControls(s DrawingState) {
+	drawPlayButton(s.live.playing)
+	drawLiveButton(0, ">|", rl.DarkGray)
+	drawLiveButton(1, "[]", rl.DarkGray)
+	drawLiveButton(2, "+", rl.DarkGray)
+	drawLiveButton(3, "-", rl.DarkGray)
+
+	status := fmt.Sprintf("%d ticks/s", s.live.TicksPerSecond())
+	color := rl.Gray
+	switch {
+	case s.live.err != nil:
+		status = "failed: " + s.live.err.Error()
+		color = rl.Red
+	case s.live.playing:
+		status = fmt.Sprintf("live, tick %d | %s", s.live.ticks, status)
+		color = rl.Green
+	case s.live.enabled:
+		status = fmt.Sprintf("paused, tick %d | %s", s.live.ticks, status)
+	}
+	x, y := liveButtonPosition(3)
+	textWidth := rl.MeasureText(status, statusFontSize)
+	rl.DrawText(status, x-actionsOffset-textWidth, y+(actionButtonSize-statusFontSize)/2, statusFontSize, color)
+}
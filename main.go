@@ -1,15 +1,17 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
-)
-
-var (
-	MAX_DEFERS = 10
-	DEBUG      = false
+	"github.com/murilo-toddy/copooter/cli"
+	"github.com/murilo-toddy/copooter/render"
+	"github.com/murilo-toddy/copooter/sim"
 )
 
 var (
@@ -17,14 +19,6 @@ var (
 	height = int32(1080)
 )
 
-var (
-	gridCellSize                = int32(10)
-	gridComponentImageSize      = gridCellSize * 10
-	gridComponentFontSize       = int32(8)
-	gridComponentTerminalRadius = float32(5.0)
-	gridWireWidth               = int32(4)
-)
-
 var (
 	toolkitSidebarRatio          = int32(15)
 	toolkitComponentPadding      = int32(20)
@@ -35,10 +29,14 @@ var (
 )
 
 var (
-	actionsOffset    = int32(10)
-	actionButtonSize = int32(30)
+	actionsOffset         = int32(10)
+	actionButtonSize      = int32(30)
+	actionTextButtonWidth = int32(60)
+	actionButtonFontSize  = int32(16)
 )
 
+var schematicPath = "schematic.json"
+
 const (
 	resistorResourcePath = "./resources/resistor.png"
 )
@@ -47,7 +45,7 @@ const (
 type ToolkitComponent struct {
 	resourceName string
 	resource     rl.Texture2D
-	Component
+	sim.Component
 }
 
 type State int
@@ -57,18 +55,52 @@ const (
 	StateDragging
 	StateComponentSelected
 	StateNodeSelected
-	StateSimulating
+	StateDrillDown
+	StateMovingComponent
+	StateSelecting
+	StateWireSelected
 )
 
 type DrawingState struct {
 	state             State
+	textures          *render.Textures
 	toolkitComponents []ToolkitComponent
-	components        []Component
+	toolkitScroll     int32
+	components        []sim.Component
+	wires             []*sim.Wire
+	routes            wireRoutes
 	nextComponentID   int
+	history           History
+	live              LiveSimulation
+	debug             DebugSession
 
 	draggingComponent *ToolkitComponent
-	selectedComponent *Component
+	// primary component of the selection, whose nodes and properties are
+	// edited
+	selectedComponent *sim.Component
 	selectedNode      *int
+	selection         []sim.Component
+	selectedWire      *sim.Wire
+	// points the wire being drawn from the selected node passes through
+	waypoints []sim.Position
+	// corner where the rubber band selection started, in world coordinates
+	selectionStart rl.Vector2
+	// components being moved, their positions before moving and where the
+	// grabbed one was grabbed, relative to its position in world coordinates
+	moving      []sim.Movable
+	moveStarts  []sim.Position
+	moveGrabbed int
+	moveGrab    rl.Vector2
+
+	// copies of the components last copied or cut, and how many times they
+	// were pasted
+	clipboard  []sim.Component
+	pasteCount int
+
+	hierarchyVisible  bool
+	hierarchyExpanded map[sim.Component]bool
+	// stack of components being inspected, innermost last
+	drillDown []sim.Component
 }
 
 func (d *DrawingState) Log() {
@@ -84,6 +116,14 @@ func (d *DrawingState) Log() {
 		state = "component-selected"
 	case StateNodeSelected:
 		state = "node-selected"
+	case StateDrillDown:
+		state = "drill-down"
+	case StateMovingComponent:
+		state = "moving-component"
+	case StateSelecting:
+		state = "selecting"
+	case StateWireSelected:
+		state = "wire-selected"
 	}
 	logMessage += fmt.Sprintf("Current state: %s", state)
 
@@ -103,44 +143,42 @@ func (d *DrawingState) Log() {
 	fmt.Println(logMessage)
 }
 
-func addComponent(s *DrawingState, c Component, p Position) {
+// Name of a new component called base, numbered after the placed components
+// and the pending ones about to be placed
+func nextComponentName(s *DrawingState, base string, pending []string) string {
 	n := 1
 	for _, existingComponent := range s.components {
-		if strings.HasPrefix(existingComponent.GetID().Name, c.GetID().Name) {
+		if strings.HasPrefix(existingComponent.GetID().Name, base) {
 			n++
 		}
 	}
-	newName := fmt.Sprintf("%s %d", c.GetID().Name, n)
-	s.components = append(s.components, c.Clone(ComponentID{
-		Name: newName, ID: getNextID(s), Position: Position{p.X, p.Y},
-	}))
-}
-
-func loadTextureWithSize(resourcePath string, width, height int32) (t rl.Texture2D) {
-	if resourcePath != "" {
-		image := rl.LoadImage(resourcePath)
-		rl.ImageResize(image, width, height)
-		t = rl.LoadTextureFromImage(image)
+	for _, name := range pending {
+		if strings.HasPrefix(name, base) {
+			n++
+		}
 	}
-	return
+	return fmt.Sprintf("%s %d", base, n)
 }
 
-func loadToolboxTexture(resourcePath string) rl.Texture2D {
-	return loadTextureWithSize(resourcePath, toolkitComponentImageSize, toolkitComponentImageSize)
+func addComponent(s *DrawingState, c sim.Component, p sim.Position) {
+	newName := nextComponentName(s, c.GetID().Name, nil)
+	s.history.Execute(s, &placeComponentCommand{c.Clone(sim.ComponentID{
+		Name: newName, ID: getNextID(s), Position: sim.Position{X: p.X, Y: p.Y},
+	})})
 }
 
-func loadGridTexture(resourcePath string) rl.Texture2D {
-	return loadTextureWithSize(resourcePath, gridComponentImageSize, gridComponentImageSize)
+func loadToolboxTexture(resourcePath string) rl.Texture2D {
+	return render.LoadTexture(resourcePath, toolkitComponentImageSize, toolkitComponentImageSize)
 }
 
 // Select component from toolbox
 func checkToolkitComponentSelected(s *DrawingState, pos rl.Vector2) {
 	if rl.IsMouseButtonDown(rl.MouseButtonLeft) {
 		if int32(pos.X) < toolkitSidebarSize {
-			componentIndex := int32(pos.Y) / toolkitComponentBoxSize
+			componentIndex := (int32(pos.Y) + s.toolkitScroll) / toolkitComponentBoxSize
 			if componentIndex < int32(len(s.toolkitComponents)) {
 				selectedComponent := s.toolkitComponents[componentIndex]
-				selectedComponent.resource = loadGridTexture(selectedComponent.resourceName)
+				selectedComponent.resource = render.LoadGridTexture(selectedComponent.resourceName)
 
 				// enter dragging state
 				s.draggingComponent = &selectedComponent
@@ -150,13 +188,28 @@ func checkToolkitComponentSelected(s *DrawingState, pos rl.Vector2) {
 	}
 }
 
+// Scroll toolbox when it has more components than fit on screen
+func checkToolkitScroll(s *DrawingState, pos rl.Vector2) {
+	if int32(pos.X) >= toolkitSidebarSize {
+		return
+	}
+	s.toolkitScroll -= int32(rl.GetMouseWheelMove() * float32(toolkitComponentBoxSize) / 2)
+	maxScroll := int32(len(s.toolkitComponents))*toolkitComponentBoxSize - height
+	if s.toolkitScroll > maxScroll {
+		s.toolkitScroll = maxScroll
+	}
+	if s.toolkitScroll < 0 {
+		s.toolkitScroll = 0
+	}
+}
+
 // Drop toolbox component into schematic
 func checkComponentDropped(s *DrawingState, pos rl.Vector2) {
 	if rl.IsMouseButtonReleased(rl.MouseButtonLeft) && s.draggingComponent != nil {
 		if isInsideSchematic(pos) {
 			x, y := snapToGrid(pos)
 			component := *s.draggingComponent
-			addComponent(s, component, Position{x, y})
+			addComponent(s, component, sim.Position{X: x, Y: y})
 		}
 		// release dragging component and reset state to idle
 		s.draggingComponent = nil
@@ -164,35 +217,36 @@ func checkComponentDropped(s *DrawingState, pos rl.Vector2) {
 	}
 }
 
+// Components hidden under the toolkit or the hierarchy panel are not clicked
 func checkSchematicComponentSelected(s *DrawingState, pos rl.Vector2) {
-	if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
+	if rl.IsMouseButtonReleased(rl.MouseButtonLeft) && isOverCanvas(s, pos) {
+		var clicked sim.Component
 		for _, component := range s.components {
 			if isInsideComponent(pos, component) {
-				s.selectedComponent = &component
-				s.state = StateComponentSelected
+				clicked = component
 			}
 		}
+		if clicked != nil {
+			clickComponent(s, clicked)
+		}
 	}
 }
 
 func checkNewComponentSelected(s *DrawingState, pos rl.Vector2) {
 	if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
 		for _, component := range s.components {
-			if isInsideComponent(pos, component) {
-				s.selectedComponent = &component
-				s.state = StateComponentSelected
+			if isOverCanvas(s, pos) && isInsideComponent(pos, component) {
+				clickComponent(s, component)
 				return
 			}
 		}
-		s.selectedComponent = nil
-		s.selectedNode = nil
-		s.state = StateIdle
+		clearSelection(s)
 	}
 }
 
 func checkNodeSelected(s *DrawingState, pos rl.Vector2) {
 	if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
-		if s.selectedComponent != nil && isInsideComponent(pos, *s.selectedComponent) {
+		if s.selectedComponent != nil && isOverCanvas(s, pos) && isInsideComponent(pos, *s.selectedComponent) {
 			for nodeIndex, node := range (*s.selectedComponent).Nodes() {
 				if isInsideNode(pos, node) {
 					s.selectedNode = &nodeIndex
@@ -206,19 +260,33 @@ func checkNodeSelected(s *DrawingState, pos rl.Vector2) {
 
 func checkChangeInputComponentState(s *DrawingState) {
 	if rl.IsKeyPressed(rl.KeyEnter) {
-		terminal, ok := (*s.selectedComponent).(*Terminal)
+		terminal, ok := (*s.selectedComponent).(*sim.Terminal)
 		if !ok {
 			return
 		}
 
-		var newState NodeState
+		var newState sim.NodeState
 		switch terminal.Node.State {
-		case Off:
-			newState = On
-		case On:
-			newState = Off
+		case sim.Off:
+			newState = sim.On
+		case sim.On:
+			newState = sim.Off
 		}
-		terminal.state = newState
+		s.history.Execute(s, &setInputStateCommand{terminal, terminal.State(), newState})
+	}
+}
+
+func checkToggleInstanceLevel(s *DrawingState) {
+	if rl.IsKeyPressed(rl.KeyL) {
+		instance, ok := (*s.selectedComponent).(*sim.BlockInstance)
+		if !ok {
+			return
+		}
+		level := sim.LevelTransistor
+		if instance.Level == sim.LevelTransistor {
+			level = sim.LevelBehavioral
+		}
+		s.history.Execute(s, &setInstanceLevelCommand{instance, instance.Level, level})
 	}
 }
 
@@ -228,72 +296,168 @@ func checkConnectNodes(s *DrawingState, pos rl.Vector2) {
 			fmt.Println("Attempting to connect terminal but selected component is nil")
 			return
 		}
+		if !isOverCanvas(s, pos) {
+			clearSelection(s)
+			return
+		}
 		selectedTerminal := (*s.selectedComponent).Nodes()[*s.selectedNode]
 		for _, component := range s.components {
 			for _, term := range component.Nodes() {
 				if isInsideNode(pos, term) {
-					selectedTerminal.Connect(term)
+					if term != selectedTerminal && !slices.Contains(selectedTerminal.Connections(), term) {
+						s.history.Execute(s, &addWireCommand{sim.NewWire(selectedTerminal, term, s.waypoints...)})
+					}
+					s.waypoints = nil
 					return
 				}
 			}
 		}
-		s.selectedComponent = nil
-		s.selectedNode = nil
-		s.state = StateIdle
+		for _, component := range s.components {
+			if isInsideComponent(pos, component) {
+				clickComponent(s, component)
+				return
+			}
+		}
+		// clicking an empty spot routes the wire through it
+		s.waypoints = append(s.waypoints, snapWaypoint(pos))
 	}
 }
 
+// D deletes every wire of the selected node, a single wire is deleted by
+// selecting it
 func checkRemoveConnections(s *DrawingState) {
 	if rl.IsKeyPressed(rl.KeyD) {
-		(*s.selectedComponent).Nodes()[*s.selectedNode].DisconnectAll()
+		node := (*s.selectedComponent).Nodes()[*s.selectedNode]
+		var commands []EditCommand
+		for _, wire := range s.wires {
+			if wire.Touches(node) {
+				commands = append(commands, &deleteWireCommand{wire})
+			}
+		}
+		switch len(commands) {
+		case 0:
+		case 1:
+			s.history.Execute(s, commands[0])
+		default:
+			s.history.Execute(s, &groupCommand{commands, "disconnect " + node.Parent.GetID().Name})
+		}
 	}
 }
 
-// Select component from toolbox
-func checkPlayButtonSelected(s *DrawingState, pos rl.Vector2) {
-	if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
-		if isInsideSquare(pos, width-actionsOffset-actionButtonSize, actionsOffset, actionButtonSize, actionButtonSize) {
-			s.state = StateSimulating
+func isPlayButtonClicked(pos rl.Vector2) bool {
+	return rl.IsMouseButtonReleased(rl.MouseButtonLeft) &&
+		isInsideSquare(pos, width-actionsOffset-actionButtonSize, actionsOffset, actionButtonSize, actionButtonSize)
+}
+
+// Position of the text action buttons, laid out right to left next to the play button
+func textButtonPosition(index int32) (int32, int32) {
+	return width - actionsOffset - actionButtonSize - (index+1)*(actionTextButtonWidth+actionsOffset), actionsOffset
+}
+
+func isTextButtonClicked(pos rl.Vector2, index int32) bool {
+	x, y := textButtonPosition(index)
+	return rl.IsMouseButtonReleased(rl.MouseButtonLeft) &&
+		isInsideSquare(pos, x, y, actionTextButtonWidth, actionButtonSize)
+}
+
+func drawTextButton(index int32, label string, color rl.Color) {
+	x, y := textButtonPosition(index)
+	rl.DrawRectangle(x, y, actionTextButtonWidth, actionButtonSize, color)
+	textWidth := rl.MeasureText(label, actionButtonFontSize)
+	rl.DrawText(label, x+(actionTextButtonWidth-textWidth)/2, y+(actionButtonSize-actionButtonFontSize)/2, actionButtonFontSize, rl.White)
+}
+
+func isControlDown() bool {
+	return rl.IsKeyDown(rl.KeyLeftControl) || rl.IsKeyDown(rl.KeyRightControl)
+}
+
+func checkSaveSelected(s *DrawingState, pos rl.Vector2) {
+	if isTextButtonClicked(pos, 0) || (isControlDown() && rl.IsKeyPressed(rl.KeyS)) {
+		if err := sim.SaveSchematic(schematicPath, s.components, s.wires...); err != nil {
+			fmt.Println("Failed to save schematic: ", err.Error())
+			return
 		}
+		fmt.Println("Saved schematic to ", schematicPath)
 	}
 }
 
-func drawComponentsToolbox(drawableComponents []ToolkitComponent) {
-	rl.DrawRectangle(0, 0, toolkitSidebarSize, height, rl.NewColor(48, 48, 48, 255))
-	for i, component := range drawableComponents {
-		rl.DrawTexture(component.resource, toolkitComponentPadding/2, int32(i)*toolkitComponentBoxSize+toolkitComponentPadding/2, rl.White)
-		rl.DrawText(component.Component.GetID().Name, toolkitComponentPadding/2, int32(i)*toolkitComponentBoxSize+toolkitSidebarSize, toolkitComponentNameFontSize, rl.White)
+func checkOpenSelected(s *DrawingState, pos rl.Vector2) {
+	if isTextButtonClicked(pos, 1) || (isControlDown() && rl.IsKeyPressed(rl.KeyO)) {
+		schematic, err := sim.OpenSchematic(schematicPath)
+		if err != nil {
+			fmt.Println("Failed to open schematic: ", err.Error())
+			return
+		}
+		components, wires, err := schematic.BuildWires()
+		if err != nil {
+			fmt.Println("Failed to open schematic: ", err.Error())
+			return
+		}
+		s.components = components
+		s.wires = wires
+		s.nextComponentID = schematic.MaxID() + 1
+		s.history.Clear()
+		clearSelection(s)
+		s.drillDown = nil
 	}
 }
 
-func drawGridLines() {
-	for i := toolkitSidebarSize + gridCellSize; i < width; i += gridCellSize {
-		rl.DrawLine(i, 0, i, height, rl.NewColor(48, 48, 48, 255))
-	}
-	for i := int32(0); i < height; i += gridCellSize {
-		rl.DrawLine(toolkitSidebarSize, i, width, i, rl.NewColor(48, 48, 48, 255))
+func checkExportSpiceSelected(s *DrawingState, pos rl.Vector2) {
+	if isTextButtonClicked(pos, 2) {
+		path := strings.TrimSuffix(schematicPath, filepath.Ext(schematicPath)) + ".cir"
+		f, err := os.Create(path)
+		if err != nil {
+			fmt.Println("Failed to export SPICE netlist: ", err.Error())
+			return
+		}
+		defer f.Close()
+		if err := sim.WriteSpiceNetlist(f, s.components, sim.DefaultSpiceOptions); err != nil {
+			fmt.Println("Failed to export SPICE netlist: ", err.Error())
+			return
+		}
+		fmt.Println("Exported SPICE netlist to ", path)
 	}
 }
 
-func getTerminalCoordinates(n *Node) (float32, float32) {
-	x, y := n.Parent.GetPosition()
-	return float32(x) + float32(gridComponentImageSize)*n.OffsetX, float32(y) + float32(gridComponentImageSize)*n.OffsetY
+// Components collapsed in the hierarchy panel are also collapsed in the graph
+func hierarchyCollapsed(s *DrawingState, components []sim.Component, collapsed map[sim.Component]bool) {
+	for _, c := range components {
+		if !s.hierarchyExpanded[c] {
+			collapsed[c] = true
+		}
+		hierarchyCollapsed(s, sim.Subcomponents(c), collapsed)
+	}
 }
 
-func minAndDist(v1, v2 int32) (int32, int32) {
-	if v1 < v2 {
-		return v1, v2 - v1
+func checkExportDotSelected(s *DrawingState, pos rl.Vector2) {
+	if isTextButtonClicked(pos, 3) {
+		path := strings.TrimSuffix(schematicPath, filepath.Ext(schematicPath)) + ".dot"
+		f, err := os.Create(path)
+		if err != nil {
+			fmt.Println("Failed to export DOT graph: ", err.Error())
+			return
+		}
+		defer f.Close()
+		options := sim.DotOptions{Collapsed: map[sim.Component]bool{}}
+		hierarchyCollapsed(s, s.components, options.Collapsed)
+		if err := sim.WriteDot(f, s.components, options); err != nil {
+			fmt.Println("Failed to export DOT graph: ", err.Error())
+			return
+		}
+		fmt.Println("Exported DOT graph to ", path)
 	}
-	return v2, v1 - v2
 }
 
-func drawWire(fromX, fromY, toX, toY int32, color rl.Color) {
-	if fromX == toX {
-		startY, dy := minAndDist(fromY, toY)
-		rl.DrawRectangle(fromX-gridWireWidth/2, startY-gridWireWidth/2, gridWireWidth, dy+gridWireWidth, color)
-	} else if fromY == toY {
-		startX, dx := minAndDist(fromX, toX)
-		rl.DrawRectangle(startX-gridWireWidth/2, fromY-gridWireWidth/2, dx+gridWireWidth, gridWireWidth, color)
+func drawComponentsToolbox(drawableComponents []ToolkitComponent, scroll int32) {
+	rl.DrawRectangle(0, 0, toolkitSidebarSize, height, rl.NewColor(48, 48, 48, 255))
+	for i, component := range drawableComponents {
+		y := int32(i)*toolkitComponentBoxSize - scroll
+		if component.resourceName == "" {
+			// blocks have no resource, draw a placeholder box instead
+			rl.DrawRectangleLines(toolkitComponentPadding/2, y+toolkitComponentPadding/2, toolkitComponentImageSize, toolkitComponentImageSize, rl.White)
+		}
+		rl.DrawTexture(component.resource, toolkitComponentPadding/2, y+toolkitComponentPadding/2, rl.White)
+		rl.DrawText(component.Component.GetID().Name, toolkitComponentPadding/2, y+toolkitSidebarSize, toolkitComponentNameFontSize, rl.White)
 	}
 }
 
@@ -302,49 +466,44 @@ func isInsideSquare(pos rl.Vector2, x, y, w, h int32) bool {
 	return posX >= x && posX <= x+w && posY >= y && posY <= y+h
 }
 
-func isInsideComponent(pos rl.Vector2, c Component) bool {
+// Hit tests on placed components take the mouse position on the screen and
+// map it through the camera
+func isInsideComponent(pos rl.Vector2, c sim.Component) bool {
 	x, y := c.GetPosition()
-	return isInsideSquare(pos, x, y, gridComponentImageSize, gridComponentImageSize)
+	return isInsideSquare(screenToWorld(pos), x, y, render.ComponentImageSize, render.ComponentImageSize)
 }
 
-func isInsideNode(pos rl.Vector2, term *Node) bool {
-	termCenterX, termCenterY := getTerminalCoordinates(term)
-	r := gridComponentTerminalRadius
+func isInsideNode(pos rl.Vector2, term *sim.Node) bool {
+	pos = screenToWorld(pos)
+	termCenterX, termCenterY := render.TerminalCoordinates(term)
+	r := render.TerminalRadius
 	return pos.X >= termCenterX-r && pos.X <= termCenterX+r &&
 		pos.Y >= termCenterY-r && pos.Y <= termCenterY+r
 }
 
 func isInsideSchematic(pos rl.Vector2) bool {
-	// TODO: allow adding components close to the toolkit sidebar
-	return int32(pos.X) > toolkitSidebarSize+gridComponentImageSize/2
+	return int32(pos.X) > toolkitSidebarSize
 }
 
+// Position on the grid of a component dropped centered on the mouse
 func snapToGrid(pos rl.Vector2) (int32, int32) {
-	x := int32(pos.X) - toolkitComponentImageSize/2 - toolkitSidebarSize
-	y := int32(pos.Y) - toolkitComponentImageSize/2
-	return (x / gridCellSize * gridCellSize) + toolkitSidebarSize, y / gridCellSize * gridCellSize
+	pos = screenToWorld(pos)
+	return snapCoordinates(int32(pos.X)-toolkitComponentImageSize/2, int32(pos.Y)-toolkitComponentImageSize/2)
 }
 
-func drawComponentOutline(c Component, color rl.Color) {
-	x, y := c.GetPosition()
-	rl.DrawRectangleLines(x, y, gridComponentImageSize, gridComponentImageSize, color)
-}
-
-func drawTerminal(c Component, n *Node, color rl.Color) {
-	x, y := c.GetPosition()
-	rl.DrawCircle(
-		x+int32(float32(gridComponentImageSize)*n.OffsetX),
-		y+int32(float32(gridComponentImageSize)*n.OffsetY),
-		gridComponentTerminalRadius,
-		color,
-	)
-}
-
-func drawPlayButton() {
+// Draws the play button, or the pause button while playing
+func drawPlayButton(playing bool) {
 	x := width - actionsOffset - actionButtonSize
 	y := actionsOffset
 	rl.DrawRectangle(x, y, actionButtonSize, actionButtonSize, rl.Green)
 	playButtonSymbolOffset := int32(3)
+	if playing {
+		barWidth := actionButtonSize / 4
+		barHeight := actionButtonSize - 4*playButtonSymbolOffset
+		rl.DrawRectangle(x+barWidth, y+2*playButtonSymbolOffset, barWidth, barHeight, rl.White)
+		rl.DrawRectangle(x+actionButtonSize-2*barWidth, y+2*playButtonSymbolOffset, barWidth, barHeight, rl.White)
+		return
+	}
 	rl.DrawTriangle(
 		rl.Vector2{X: float32(x + playButtonSymbolOffset), Y: float32(y + playButtonSymbolOffset)},
 		rl.Vector2{X: float32(x + playButtonSymbolOffset), Y: float32(y + actionButtonSize - playButtonSymbolOffset)},
@@ -359,7 +518,7 @@ func getNextID(s *DrawingState) string {
 	return fmt.Sprintf("%d", id)
 }
 
-func NewToolkitComponent(resourceName string, component Component) ToolkitComponent {
+func NewToolkitComponent(resourceName string, component sim.Component) ToolkitComponent {
 	return ToolkitComponent{
 		resourceName: resourceName,
 		resource:     loadToolboxTexture(resourceName),
@@ -367,50 +526,81 @@ func NewToolkitComponent(resourceName string, component Component) ToolkitCompon
 	}
 }
 
+func NewToolkitBlocks() (toolkitComponents []ToolkitComponent) {
+	for _, block := range append(slices.Clone(sim.Blocks), sim.SequentialBlocks...) {
+		toolkitComponents = append(toolkitComponents, NewToolkitComponent(
+			"", sim.NewBlockInstance(block.Name, block, nil, sim.LevelTransistor),
+		))
+	}
+	return
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := cli.Commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
+	flag.StringVar(&schematicPath, "schematic", schematicPath, "file used by the Save and Open actions")
+	flag.Parse()
+
+	// simulations print their meter readings and debug traces on the terminal
+	sim.DefaultTracer.Sink = sim.NewTextSink(os.Stdout)
+
 	rl.InitWindow(width, height, "copooter")
 	defer rl.CloseWindow()
 
 	s := DrawingState{
-		state: StateIdle,
+		state:             StateIdle,
+		live:              NewLiveSimulation(),
+		hierarchyExpanded: map[sim.Component]bool{},
+		textures:          render.NewTextures(),
 		toolkitComponents: []ToolkitComponent{
-			// TODO: remove copy of resource name
 			NewToolkitComponent(
 				resistorResourcePath,
-				NewDrawableResistor(
+				sim.NewDrawableResistor(
 					"Resistor",
-					&Node{OffsetX: 0.0, OffsetY: 0.5}, &Node{OffsetX: 1.0, OffsetY: 0.5},
-					resistorResourcePath, "./resources/resistor-selected.png",
+					&sim.Node{OffsetX: 0.0, OffsetY: 0.5}, &sim.Node{OffsetX: 1.0, OffsetY: 0.5},
 				),
 			),
 			NewToolkitComponent(
 				"./resources/transistor.jpg",
-				NewDrawableTransistor(
+				sim.NewDrawableTransistor(
 					"Transistor",
-					&Node{OffsetX: 0.6, OffsetY: 0.05},
-					&Node{OffsetX: 0.05, OffsetY: 0.5},
-					&Node{OffsetX: 0.6, OffsetY: 0.95},
-					"./resources/transistor.jpg",
+					&sim.Node{OffsetX: 0.6, OffsetY: 0.05},
+					&sim.Node{OffsetX: 0.05, OffsetY: 0.5},
+					&sim.Node{OffsetX: 0.6, OffsetY: 0.95},
 				),
 			),
 			NewToolkitComponent(
 				"./resources/source.png",
-				NewDrawableSource("Source", &Node{OffsetX: 0.5, OffsetY: 0.05}, "./resources/source.png"),
+				sim.NewDrawableSource("Source", &sim.Node{OffsetX: 0.5, OffsetY: 0.05}),
 			),
 			NewToolkitComponent(
 				"./resources/ground.png",
-				NewDrawableGround("Ground", &Node{OffsetX: 0.5, OffsetY: 0.05}, "./resources/ground.png"),
+				sim.NewDrawableGround("Ground", &sim.Node{OffsetX: 0.5, OffsetY: 0.05}),
 			),
 			NewToolkitComponent(
 				"./resources/meter.jpg",
-				NewDrawableMultimeter("Multimeter", &Node{OffsetX: 0.3, OffsetY: 0.5}, "./resources/meter.jpg"),
+				sim.NewDrawableMultimeter("Multimeter", &sim.Node{OffsetX: 0.3, OffsetY: 0.5}),
 			),
 			NewToolkitComponent(
 				"./resources/input.jpg",
-				NewDrawableInput("Input", &Node{OffsetX: 0.7, OffsetY: 0.5}, Off, "./resources/input.jpg"),
+				sim.NewDrawableInput("Input", &sim.Node{OffsetX: 0.7, OffsetY: 0.5}, sim.Off),
+			),
+			NewToolkitComponent(
+				"./resources/input.jpg",
+				sim.NewDrawableClock("Clock", &sim.Node{OffsetX: 0.7, OffsetY: 0.5}),
 			),
 		},
 	}
+	// every placed or loaded component is drawn with the texture of its toolkit entry
+	for _, toolkitComponent := range s.toolkitComponents {
+		s.textures.Load(render.Kind(toolkitComponent.Component), toolkitComponent.resourceName)
+	}
+	s.textures.LoadSelected("Resistor", "./resources/resistor-selected.png")
+	s.toolkitComponents = append(s.toolkitComponents, NewToolkitBlocks()...)
 
 	for !rl.WindowShouldClose() {
 		rl.BeginDrawing()
@@ -421,63 +611,76 @@ func main() {
 		// s.Log()
 		switch s.state {
 		case StateIdle:
+			checkUndoRedo(&s)
+			checkClipboardShortcuts(&s)
+			checkToolkitScroll(&s, mousePos)
 			checkToolkitComponentSelected(&s, mousePos)
-			checkSchematicComponentSelected(&s, mousePos)
+			if !checkHierarchyPanelClicked(&s, mousePos) {
+				checkSchematicComponentSelected(&s, mousePos)
+				checkStartSelecting(&s, mousePos)
+			}
 		case StateDragging:
 			checkComponentDropped(&s, mousePos)
 		case StateComponentSelected:
+			checkUndoRedo(&s)
 			checkNewComponentSelected(&s, mousePos)
 			checkNodeSelected(&s, mousePos)
 			checkChangeInputComponentState(&s)
+			checkToggleInstanceLevel(&s)
+			checkOrientSelection(&s)
+			checkClipboardShortcuts(&s)
+			checkStartMovingSelection(&s, mousePos)
+			checkStartSelecting(&s, mousePos)
+			checkDeleteSelection(&s)
 		case StateNodeSelected:
+			checkUndoRedo(&s)
 			checkConnectNodes(&s, mousePos)
+			checkCancelWire(&s)
 			checkRemoveConnections(&s)
+		case StateDrillDown:
+			checkDrillDownActions(&s, mousePos)
+		case StateMovingComponent:
+			checkMovingSelection(&s, mousePos)
+		case StateSelecting:
+			checkSelecting(&s, mousePos)
+		case StateWireSelected:
+			checkUndoRedo(&s)
+			checkDeleteWire(&s)
 			checkNewComponentSelected(&s, mousePos)
+			checkStartSelecting(&s, mousePos)
+		}
+		if s.state != StateDrillDown {
+			checkCameraControls(&s, mousePos)
 		}
-		checkPlayButtonSelected(&s, mousePos)
+		checkToggleHierarchyPanel(&s)
+		checkLiveControls(&s, mousePos)
+		checkDebuggerControls(&s, mousePos)
+		if s.state == StateIdle {
+			checkSaveSelected(&s, mousePos)
+			checkOpenSelected(&s, mousePos)
+			checkExportSpiceSelected(&s, mousePos)
+			checkExportDotSelected(&s, mousePos)
+		}
+
+		updateLiveSimulation(&s)
 
 		// Render
+		syncWires(&s)
+		updateWireRoutes(&s)
+		rl.BeginMode2D(camera)
 		drawGridLines()
 
 		for _, component := range s.components {
-			component.Render(s)
-			for _, term := range component.Nodes() {
-				termX, termY := getTerminalCoordinates(term)
-				var color rl.Color
-				switch term.State {
-				case Off:
-					color = rl.White
-				case On:
-					color = rl.Yellow
-				case Undefined:
-					color = rl.White
-				default:
-					panic("unreachable state")
-				}
-				for _, conn := range term.connections {
-					connX, connY := getTerminalCoordinates(conn)
-					if termX > connX {
-						drawWire(int32(termX), int32(termY), int32(termX), int32(connY), color)
-						drawWire(int32(termX), int32(connY), int32(connX), int32(connY), color)
-					} else {
-						drawWire(int32(termX), int32(termY), int32(connX), int32(termY), color)
-						drawWire(int32(connX), int32(termY), int32(connX), int32(connY), color)
-					}
-				}
-			}
+			s.textures.DrawComponent(component, isSelected(s, component))
 		}
+		drawWires(s)
+		drawDebuggerHighlights(s)
 
-		drawComponentsToolbox(s.toolkitComponents)
-		// draw different things depending on current state
+		// highlights drawn over the placed components
 		switch s.state {
-		case StateDragging:
-			offset := toolkitComponentImageSize / 2
-			x, y := int32(mousePos.X)-offset, int32(mousePos.Y)-offset
-
-			rl.DrawTexture(s.draggingComponent.resource, x, y, rl.White)
 		case StateComponentSelected:
 			for _, term := range (*s.selectedComponent).Nodes() {
-				drawTerminal(*s.selectedComponent, term, rl.Red)
+				render.DrawTerminal(*s.selectedComponent, term, rl.Red)
 			}
 		case StateNodeSelected:
 			for _, component := range s.components {
@@ -488,22 +691,39 @@ func main() {
 					} else {
 						color = rl.Red
 					}
-					drawTerminal(component, term, color)
+					render.DrawTerminal(component, term, color)
 				}
 			}
-		case StateSimulating:
-			components := make([]Component, len(s.components))
-			for i, component := range s.components {
-				component.Reset()
-				components[i] = component
-			}
-			circuit := NewCircuit(components, 50, true)
-			if err := circuit.Tick(); err != nil {
-				fmt.Println("Failed to run circuit: ", err.Error())
+			drawPendingWire(s, mousePos)
+		case StateSelecting:
+			drawSelectionRectangle(s, mousePos)
+		}
+		rl.EndMode2D()
+
+		drawComponentsToolbox(s.toolkitComponents, s.toolkitScroll)
+		// draw different things depending on current state
+		switch s.state {
+		case StateDragging:
+			offset := toolkitComponentImageSize / 2
+			x, y := int32(mousePos.X)-offset, int32(mousePos.Y)-offset
+
+			if s.draggingComponent.resourceName == "" {
+				rl.DrawRectangleLines(x, y, toolkitComponentImageSize, toolkitComponentImageSize, rl.White)
 			}
-			s.state = StateIdle
+			rl.DrawTexture(s.draggingComponent.resource, x, y, rl.White)
+		case StateDrillDown:
+			drawDrillDown(s)
 		}
-		drawPlayButton()
+		drawHierarchyPanel(s)
+		drawStatusBar(s)
+		drawLiveControls(s)
+		drawDebuggerPanel(s)
+		drawTextButton(0, "Save", rl.DarkBlue)
+		drawTextButton(1, "Open", rl.DarkGray)
+		drawTextButton(2, "SPICE", rl.DarkGray)
+		drawTextButton(3, "DOT", rl.DarkGray)
+		drawTextButton(4, "Fit", rl.DarkGray)
+		drawTextButton(5, "Debug", rl.DarkGray)
 		rl.EndDrawing()
 	}
 }
@@ -0,0 +1,170 @@
+// Draws simulator components with raylib, keeping the simulation packages
+// free of any graphics dependency
+package render
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+var (
+	GridCellSize       = int32(10)
+	ComponentImageSize = GridCellSize * 10
+	ComponentFontSize  = int32(8)
+	TerminalRadius     = float32(5.0)
+	WireWidth          = int32(4)
+	JunctionRadius     = float32(6.0)
+)
+
+func LoadTexture(resourcePath string, width, height int32) (t rl.Texture2D) {
+	if resourcePath != "" {
+		image := rl.LoadImage(resourcePath)
+		rl.ImageResize(image, width, height)
+		t = rl.LoadTextureFromImage(image)
+	}
+	return
+}
+
+func LoadGridTexture(resourcePath string) rl.Texture2D {
+	return LoadTexture(resourcePath, ComponentImageSize, ComponentImageSize)
+}
+
+// Kind of component a texture is drawn for, terminals are told apart by
+// their type
+func Kind(c sim.Component) string {
+	switch c := c.(type) {
+	case *sim.Terminal:
+		return c.Type()
+	case *sim.Meter:
+		return "Multimeter"
+	case *sim.Resistor:
+		return "Resistor"
+	case *sim.Transistor:
+		return "Transistor"
+	case *sim.BlockInstance:
+		return "Block"
+	}
+	return ""
+}
+
+// Grid textures of every kind of component, shared by all components of
+// that kind
+type Textures struct {
+	idle     map[string]rl.Texture2D
+	selected map[string]rl.Texture2D
+}
+
+func NewTextures() *Textures {
+	return &Textures{
+		idle:     map[string]rl.Texture2D{},
+		selected: map[string]rl.Texture2D{},
+	}
+}
+
+func (t *Textures) Load(kind, resourcePath string) {
+	t.idle[kind] = LoadGridTexture(resourcePath)
+}
+
+// Texture drawn over a selected component instead of the outline
+func (t *Textures) LoadSelected(kind, resourcePath string) {
+	t.selected[kind] = LoadGridTexture(resourcePath)
+}
+
+func (t *Textures) DrawComponent(c sim.Component, selected bool) {
+	x, y := c.GetPosition()
+	switch c := c.(type) {
+	case *sim.Terminal, *sim.Meter, *sim.Resistor, *sim.Transistor:
+		kind := Kind(c)
+		DrawOrientedTexture(t.idle[kind], x, y, c.GetID().Orientation)
+		rl.DrawText(c.GetID().Name, x, y+ComponentImageSize, ComponentFontSize, rl.White)
+		if !selected {
+			return
+		}
+		if texture, ok := t.selected[kind]; ok {
+			DrawOrientedTexture(texture, x, y, c.GetID().Orientation)
+		} else {
+			DrawComponentOutline(c, rl.Yellow)
+		}
+	case *sim.BlockInstance:
+		rl.DrawRectangleLines(x, y, ComponentImageSize, ComponentImageSize, rl.White)
+		rl.DrawText(c.Block.Name, x+ComponentFontSize, y+ComponentImageSize/2-ComponentFontSize, ComponentFontSize, rl.White)
+		rl.DrawText(c.Level.String(), x+ComponentFontSize, y+ComponentImageSize/2+ComponentFontSize/2, ComponentFontSize, rl.Gray)
+		rl.DrawText(c.Name, x, y+ComponentImageSize, ComponentFontSize, rl.White)
+		if selected {
+			DrawComponentOutline(c, rl.Yellow)
+		}
+	}
+	// TODO: draw custom and behavioral components
+}
+
+// Draws a grid texture at x, y mirrored and rotated around its center
+func DrawOrientedTexture(texture rl.Texture2D, x, y int32, o sim.Orientation) {
+	source := rl.NewRectangle(0, 0, float32(texture.Width), float32(texture.Height))
+	if o.Flipped {
+		source.Width = -source.Width
+	}
+	half := float32(ComponentImageSize) / 2
+	dest := rl.NewRectangle(float32(x)+half, float32(y)+half, float32(ComponentImageSize), float32(ComponentImageSize))
+	rl.DrawTexturePro(texture, source, dest, rl.NewVector2(half, half), o.Degrees(), rl.White)
+}
+
+func DrawComponentOutline(c sim.Component, color rl.Color) {
+	x, y := c.GetPosition()
+	rl.DrawRectangleLines(x, y, ComponentImageSize, ComponentImageSize, color)
+}
+
+func TerminalCoordinates(n *sim.Node) (float32, float32) {
+	x, y := n.Parent.GetPosition()
+	return float32(x) + float32(ComponentImageSize)*n.OffsetX, float32(y) + float32(ComponentImageSize)*n.OffsetY
+}
+
+func DrawTerminal(c sim.Component, n *sim.Node, color rl.Color) {
+	x, y := c.GetPosition()
+	rl.DrawCircle(
+		x+int32(float32(ComponentImageSize)*n.OffsetX),
+		y+int32(float32(ComponentImageSize)*n.OffsetY),
+		TerminalRadius,
+		color,
+	)
+}
+
+func minAndDist(v1, v2 int32) (int32, int32) {
+	if v1 < v2 {
+		return v1, v2 - v1
+	}
+	return v2, v1 - v2
+}
+
+// Draws a horizontal or vertical wire segment
+func DrawWire(fromX, fromY, toX, toY int32, color rl.Color) {
+	if fromX == toX {
+		startY, dy := minAndDist(fromY, toY)
+		rl.DrawRectangle(fromX-WireWidth/2, startY-WireWidth/2, WireWidth, dy+WireWidth, color)
+	} else if fromY == toY {
+		startX, dx := minAndDist(fromX, toX)
+		rl.DrawRectangle(startX-WireWidth/2, fromY-WireWidth/2, dx+WireWidth, WireWidth, color)
+	}
+}
+
+// Draws the orthogonal path of a routed wire
+func DrawWirePath(path []sim.Position, color rl.Color) {
+	for i := 1; i < len(path); i++ {
+		DrawWire(path[i-1].X, path[i-1].Y, path[i].X, path[i].Y, color)
+	}
+}
+
+// Dot drawn where three or more wires meet
+func DrawJunction(p sim.Position, color rl.Color) {
+	rl.DrawCircle(p.X, p.Y, JunctionRadius, color)
+}
+
+func NodeStateColor(n *sim.Node) rl.Color {
+	switch n.State {
+	case sim.On:
+		return rl.Yellow
+	case sim.Off:
+		return rl.White
+	default:
+		return rl.Gray
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/murilo-toddy/copooter/render"
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+var (
+	// Rubber bands smaller than this are clicks on an empty spot
+	selectionClickSize = int32(3)
+	// Distance between pasted components and the ones they were copied from
+	pasteOffset = render.GridCellSize * 4
+)
+
+func isShiftDown() bool {
+	return rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift)
+}
+
+// Selects the components, the last one becoming the primary component. An
+// empty selection goes back to idle
+func selectComponents(s *DrawingState, components ...sim.Component) {
+	s.selection = components
+	s.selectedNode = nil
+	s.selectedWire = nil
+	s.waypoints = nil
+	if len(components) == 0 {
+		s.selectedComponent = nil
+		s.state = StateIdle
+		return
+	}
+	primary := components[len(components)-1]
+	s.selectedComponent = &primary
+	s.state = StateComponentSelected
+}
+
+func clearSelection(s *DrawingState) {
+	selectComponents(s)
+}
+
+func isSelected(s DrawingState, c sim.Component) bool {
+	return slices.Contains(s.selection, c)
+}
+
+// Clicking a component selects it alone, shift-clicking adds it to the
+// selection or removes it
+func clickComponent(s *DrawingState, c sim.Component) {
+	switch {
+	case !isShiftDown():
+		selectComponents(s, c)
+	case isSelected(*s, c):
+		selectComponents(s, slices.DeleteFunc(slices.Clone(s.selection), func(selected sim.Component) bool {
+			return selected == c
+		})...)
+	default:
+		selectComponents(s, append(slices.Clone(s.selection), c)...)
+	}
+}
+
+// Pressing on an empty spot of the schematic starts a rubber band selection
+func checkStartSelecting(s *DrawingState, pos rl.Vector2) {
+	if !rl.IsMouseButtonPressed(rl.MouseButtonLeft) || !isOverCanvas(s, pos) {
+		return
+	}
+	for _, component := range s.components {
+		if isInsideComponent(pos, component) {
+			return
+		}
+	}
+	s.selectionStart = screenToWorld(pos)
+	s.state = StateSelecting
+}
+
+func selectionRectangle(from, to rl.Vector2) (int32, int32, int32, int32) {
+	x, y := min(int32(from.X), int32(to.X)), min(int32(from.Y), int32(to.Y))
+	return x, y, max(int32(from.X), int32(to.X)) - x, max(int32(from.Y), int32(to.Y)) - y
+}
+
+// Selects the components touched by the rubber band once released, adding
+// them to the selection while shift is held. Clicking selects the wire under
+// the mouse instead
+func checkSelecting(s *DrawingState, pos rl.Vector2) {
+	if !rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
+		return
+	}
+	var selected []sim.Component
+	if isShiftDown() {
+		selected = slices.Clone(s.selection)
+	}
+	x, y, w, h := selectionRectangle(s.selectionStart, screenToWorld(pos))
+	isClick := float32(max(w, h))*camera.Zoom <= float32(selectionClickSize)
+	if wire := wireAt(s, pos); isClick && !isShiftDown() && wire != nil {
+		selectWire(s, wire)
+		return
+	}
+	if !isClick {
+		size := render.ComponentImageSize
+		for _, component := range s.components {
+			cx, cy := component.GetPosition()
+			touched := cx <= x+w && cx+size >= x && cy <= y+h && cy+size >= y
+			if touched && !slices.Contains(selected, component) {
+				selected = append(selected, component)
+			}
+		}
+	}
+	selectComponents(s, selected...)
+}
+
+// Draws the rubber band in world coordinates, inside the camera mode
+func drawSelectionRectangle(s DrawingState, pos rl.Vector2) {
+	x, y, w, h := selectionRectangle(s.selectionStart, screenToWorld(pos))
+	rl.DrawRectangle(x, y, w, h, rl.NewColor(80, 120, 200, 60))
+	rl.DrawRectangleLines(x, y, w, h, rl.NewColor(80, 120, 200, 255))
+}
+
+func movableSelection(s *DrawingState) (components []sim.Movable) {
+	for _, component := range s.selection {
+		if movable, ok := component.(sim.Movable); ok {
+			components = append(components, movable)
+		}
+	}
+	return
+}
+
+// Pressing on a selected component, away from its nodes, starts moving the
+// whole selection
+func checkStartMovingSelection(s *DrawingState, pos rl.Vector2) {
+	if !rl.IsMouseButtonPressed(rl.MouseButtonLeft) || !isOverCanvas(s, pos) {
+		return
+	}
+	moving := movableSelection(s)
+	for i, component := range moving {
+		if !isInsideComponent(pos, component) {
+			continue
+		}
+		for _, node := range component.Nodes() {
+			if isInsideNode(pos, node) {
+				return
+			}
+		}
+		s.moving = moving
+		s.moveStarts = nil
+		for _, component := range moving {
+			x, y := component.GetPosition()
+			s.moveStarts = append(s.moveStarts, sim.Position{X: x, Y: y})
+		}
+		s.moveGrabbed = i
+		world := screenToWorld(pos)
+		s.moveGrab = rl.Vector2{X: world.X - float32(s.moveStarts[i].X), Y: world.Y - float32(s.moveStarts[i].Y)}
+		s.state = StateMovingComponent
+		return
+	}
+}
+
+// Follows the mouse on the grid, recording the move once released. Releasing
+// without moving is a click on the grabbed component
+func checkMovingSelection(s *DrawingState, pos rl.Vector2) {
+	world := screenToWorld(pos)
+	x, y := snapCoordinates(int32(world.X-s.moveGrab.X), int32(world.Y-s.moveGrab.Y))
+	grabbed := s.moveStarts[s.moveGrabbed]
+	dx := x - grabbed.X
+	dy := y - grabbed.Y
+	var positions []sim.Position
+	for i, component := range s.moving {
+		position := sim.Position{X: s.moveStarts[i].X + dx, Y: s.moveStarts[i].Y + dy}
+		component.SetPosition(position)
+		positions = append(positions, position)
+	}
+	if !rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
+		return
+	}
+	if dx != 0 || dy != 0 {
+		s.history.Execute(s, &moveComponentsCommand{s.moving, s.moveStarts, positions})
+		s.state = StateComponentSelected
+	} else {
+		clickComponent(s, s.moving[s.moveGrabbed])
+	}
+	s.moving = nil
+	s.moveStarts = nil
+}
+
+// R rotates the selected components a quarter turn clockwise, F mirrors them
+func checkOrientSelection(s *DrawingState) {
+	var commands []EditCommand
+	switch {
+	case rl.IsKeyPressed(rl.KeyR):
+		for _, component := range movableSelection(s) {
+			commands = append(commands, &rotateComponentCommand{component})
+		}
+		executeGroup(s, "rotate", commands)
+	case rl.IsKeyPressed(rl.KeyF):
+		for _, component := range movableSelection(s) {
+			commands = append(commands, &flipComponentCommand{component})
+		}
+		executeGroup(s, "flip", commands)
+	}
+}
+
+func deleteSelection(s *DrawingState) {
+	var commands []EditCommand
+	for _, component := range s.selection {
+		commands = append(commands, &deleteComponentCommand{component: component})
+	}
+	executeGroup(s, "delete", commands)
+	clearSelection(s)
+}
+
+func checkDeleteSelection(s *DrawingState) {
+	if len(s.selection) > 0 && (rl.IsKeyPressed(rl.KeyDelete) || rl.IsKeyPressed(rl.KeyBackspace)) {
+		deleteSelection(s)
+	}
+}
+
+// Name of the component a numbered name was given after, such as Resistor
+// for Resistor 2
+func baseName(name string) string {
+	i := strings.LastIndex(name, " ")
+	if i < 0 {
+		return name
+	}
+	if _, err := strconv.Atoi(name[i+1:]); err != nil {
+		return name
+	}
+	return name[:i]
+}
+
+func copySelection(s *DrawingState) {
+	s.clipboard = sim.CloneGroup(s.selection, sim.Component.GetID)
+	s.pasteCount = 0
+}
+
+// Places clones of the components offset by offset, renamed and with new IDs,
+// and selects them
+func pasteComponents(s *DrawingState, components []sim.Component, offset int32) {
+	var names []string
+	clones := sim.CloneGroup(components, func(c sim.Component) sim.ComponentID {
+		id := c.GetID()
+		id.Name = nextComponentName(s, baseName(id.Name), names)
+		id.ID = getNextID(s)
+		id.X += offset
+		id.Y += offset
+		names = append(names, id.Name)
+		return id
+	})
+	var commands []EditCommand
+	for _, clone := range clones {
+		commands = append(commands, &placeComponentCommand{clone})
+	}
+	executeGroup(s, "paste", commands)
+	selectComponents(s, clones...)
+}
+
+// Ctrl+C copies the selection, Ctrl+X cuts it, Ctrl+V pastes the copied
+// components and Ctrl+D duplicates the selection
+func checkClipboardShortcuts(s *DrawingState) {
+	if !isControlDown() {
+		return
+	}
+	switch {
+	case rl.IsKeyPressed(rl.KeyC) && len(s.selection) > 0:
+		copySelection(s)
+	case rl.IsKeyPressed(rl.KeyX) && len(s.selection) > 0:
+		copySelection(s)
+		deleteSelection(s)
+	case rl.IsKeyPressed(rl.KeyV) && len(s.clipboard) > 0:
+		s.pasteCount++
+		pasteComponents(s, s.clipboard, pasteOffset*int32(s.pasteCount))
+	case rl.IsKeyPressed(rl.KeyD) && len(s.selection) > 0:
+		pasteComponents(s, s.selection, pasteOffset)
+	}
+}
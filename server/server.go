@@ -0,0 +1,302 @@
+// Serves a loaded design over HTTP for front-ends other than the GUI, with
+// REST endpoints to inspect and drive the circuit and a WebSocket streaming
+// its node changes
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+// Steps run by a single step request at most
+var MaxStepsPerRequest = 10000
+
+// Node changes queued for a WebSocket client at most, clients that fall
+// further behind are disconnected
+var MaxQueuedChanges = 1024
+
+// Bytes of a request body read at most
+const maxRequestBody = 1 << 20
+
+type Server struct {
+	// Guards the netlist and the clients, handlers run concurrently
+	mu      sync.Mutex
+	netlist *sim.Netlist
+	// Node states sent to the clients, to stream only the changes
+	last map[string]sim.NodeState
+	// Queues of the messages to each client, written by its own goroutine
+	clients map[chan []byte]bool
+	mux     *http.ServeMux
+}
+
+func NewServer(netlist *sim.Netlist) *Server {
+	s := &Server{
+		netlist: netlist,
+		last:    map[string]sim.NodeState{},
+		clients: map[chan []byte]bool{},
+		mux:     http.NewServeMux(),
+	}
+	for name, node := range netlist.Nets {
+		s.last[name] = node.State
+	}
+	s.mux.HandleFunc("GET /api/circuit", s.handleCircuit)
+	s.mux.HandleFunc("GET /api/components", s.handleComponents)
+	s.mux.HandleFunc("GET /api/nodes", s.handleNodes)
+	s.mux.HandleFunc("POST /api/inputs", s.handleInputs)
+	s.mux.HandleFunc("POST /api/step", s.handleStep)
+	s.mux.HandleFunc("GET /api/events", s.handleEvents)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type circuitInfo struct {
+	Name    string   `json:"name"`
+	Inputs  []string `json:"inputs"`
+	Outputs []string `json:"outputs"`
+	Step    uint64   `json:"step"`
+}
+
+type componentInfo struct {
+	Name  string   `json:"name"`
+	ID    string   `json:"id"`
+	Label string   `json:"label"`
+	X     int32    `json:"x"`
+	Y     int32    `json:"y"`
+	Nodes []string `json:"nodes"`
+}
+
+type nodeInfo struct {
+	Name   string `json:"name"`
+	ID     string `json:"id"`
+	State  string `json:"state"`
+	Forced bool   `json:"forced,omitempty"`
+}
+
+type stepResult struct {
+	Step    uint64            `json:"step"`
+	Outputs map[string]string `json:"outputs"`
+}
+
+// Message streamed to the WebSocket clients whenever a node changes
+type NodeChange struct {
+	Step uint64 `json:"step"`
+	Node string `json:"node"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (s *Server) handleCircuit(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, circuitInfo{
+		Name:    s.netlist.Name,
+		Inputs:  s.netlist.Inputs,
+		Outputs: s.netlist.Outputs,
+		Step:    s.netlist.Circuit.Steps(),
+	})
+}
+
+func (s *Server) handleComponents(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	components := []componentInfo{}
+	for _, component := range s.netlist.Circuit.Components() {
+		id := component.GetID()
+		info := componentInfo{
+			Name:  sim.ComponentName(component),
+			ID:    id.ID,
+			Label: sim.ComponentLabel(component),
+			X:     id.X,
+			Y:     id.Y,
+			Nodes: []string{},
+		}
+		for _, node := range component.Nodes() {
+			info.Nodes = append(info.Nodes, node.ID)
+		}
+		components = append(components, info)
+	}
+	writeJSON(w, http.StatusOK, components)
+}
+
+func (s *Server) sortedNets() []string {
+	names := make([]string, 0, len(s.netlist.Nets))
+	for name := range s.netlist.Nets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (s *Server) handleNodes(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nodes := []nodeInfo{}
+	for _, name := range s.sortedNets() {
+		node := s.netlist.Nets[name]
+		nodes = append(nodes, nodeInfo{
+			Name:   name,
+			ID:     node.ID,
+			State:  sim.StateDigit(node.State),
+			Forced: node.Forced(),
+		})
+	}
+	writeJSON(w, http.StatusOK, nodes)
+}
+
+// Sets the inputs given as a {"NAME": 0 or 1} object, applied on the next step
+func (s *Server) handleInputs(w http.ResponseWriter, r *http.Request) {
+	var values map[string]int
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+	if err := json.NewDecoder(r.Body).Decode(&values); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, fmt.Errorf("expected an object of input values: %w", err))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, value := range values {
+		if value != 0 && value != 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("input %s: invalid value %d, use 1 or 0", name, value))
+			return
+		}
+		if !slices.Contains(s.netlist.Inputs, name) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("netlist has no input %s", name))
+			return
+		}
+	}
+	inputs := map[string]string{}
+	for _, name := range s.netlist.Inputs {
+		if value, ok := values[name]; ok {
+			state := sim.NodeState(sim.Off)
+			if value == 1 {
+				state = sim.On
+			}
+			s.netlist.SetInput(name, state)
+		}
+		inputs[name] = sim.StateDigit(s.netlist.Terminals[name].State())
+	}
+	writeJSON(w, http.StatusOK, inputs)
+}
+
+// Runs ?count=N steps, 1 by default, returning the outputs after each one
+func (s *Server) handleStep(w http.ResponseWriter, r *http.Request) {
+	count := 1
+	if value := r.URL.Query().Get("count"); value != "" {
+		var err error
+		count, err = strconv.Atoi(value)
+		if err != nil || count < 1 || count > MaxStepsPerRequest {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("expected a step count between 1 and %d", MaxStepsPerRequest))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := []stepResult{}
+	for range count {
+		step := s.netlist.Circuit.Steps()
+		if err := s.netlist.Circuit.Tick(); err != nil {
+			writeError(w, http.StatusConflict, fmt.Errorf("step %d: %w", step, err))
+			return
+		}
+		s.broadcastChanges(step)
+		outputs := map[string]string{}
+		for _, output := range s.netlist.Outputs {
+			state, _ := s.netlist.Output(output)
+			outputs[output] = sim.StateDigit(state)
+		}
+		results = append(results, stepResult{step, outputs})
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// Queues the nodes changed by step to every client, dropping the ones whose
+// queue is full
+func (s *Server) broadcastChanges(step uint64) {
+	for _, name := range s.sortedNets() {
+		state := s.netlist.Nets[name].State
+		previous := s.last[name]
+		if previous == state {
+			continue
+		}
+		s.last[name] = state
+		message, _ := json.Marshal(NodeChange{
+			Step: step,
+			Node: name,
+			From: sim.StateDigit(previous),
+			To:   sim.StateDigit(state),
+		})
+		for client := range s.clients {
+			select {
+			case client <- message:
+			default:
+				s.removeClient(client)
+			}
+		}
+	}
+}
+
+// Stops streaming to client, its goroutine closes the connection once the
+// queued messages are written. Must be called with s.mu held
+func (s *Server) removeClient(client chan []byte) {
+	if s.clients[client] {
+		delete(s.clients, client)
+		close(client)
+	}
+}
+
+// Streams node changes to the client until it disconnects
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	// registered before the handshake so the client sees every step it
+	// requests after connecting, the changes wait in its queue meanwhile
+	client := make(chan []byte, MaxQueuedChanges)
+	s.mu.Lock()
+	s.clients[client] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.removeClient(client)
+		s.mu.Unlock()
+	}()
+
+	conn, err := acceptWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	go conn.writeMessages(client)
+	conn.discardMessages()
+}
+
+// Disconnects every WebSocket client
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for client := range s.clients {
+		s.removeClient(client)
+	}
+}
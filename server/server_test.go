@@ -0,0 +1,230 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+var testHalfAdderDesign = filepath.Join("..", "sim", "testdata", "half_adder.circuit")
+
+func newTestServer(t *testing.T) *httptest.Server {
+	netlist, err := sim.LoadDesign(testHalfAdderDesign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(netlist)
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		s.Close()
+		ts.Close()
+	})
+	return ts
+}
+
+func request(t *testing.T, method, url, body string, expectedStatus int, result any) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	content, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != expectedStatus {
+		t.Fatalf("%s %s returned %d instead of %d: %s", method, url, resp.StatusCode, expectedStatus, content)
+	}
+	if result != nil {
+		if err := json.Unmarshal(content, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestServerEndpoints(t *testing.T) {
+	ts := newTestServer(t)
+
+	var circuit circuitInfo
+	request(t, "GET", ts.URL+"/api/circuit", "", http.StatusOK, &circuit)
+	if circuit.Name != "HalfAdder" || strings.Join(circuit.Inputs, " ") != "a b" || strings.Join(circuit.Outputs, " ") != "sum carry" {
+		t.Errorf("unexpected circuit %+v", circuit)
+	}
+
+	var components []componentInfo
+	request(t, "GET", ts.URL+"/api/components", "", http.StatusOK, &components)
+	names := map[string]bool{}
+	for _, component := range components {
+		names[component.Name] = true
+	}
+	for _, name := range []string{"a", "b", "sum", "carry", "x0", "a0"} {
+		if !names[name] {
+			t.Errorf("expected component %s in %+v", name, components)
+		}
+	}
+
+	var inputs map[string]string
+	request(t, "POST", ts.URL+"/api/inputs", `{"a": 1}`, http.StatusOK, &inputs)
+	if inputs["a"] != "1" || inputs["b"] != "0" {
+		t.Errorf("unexpected inputs %v", inputs)
+	}
+	request(t, "POST", ts.URL+"/api/inputs", `{"c": 1}`, http.StatusNotFound, nil)
+	// supply nets are terminals but not inputs
+	request(t, "POST", ts.URL+"/api/inputs", `{"vdd": 0}`, http.StatusNotFound, nil)
+	request(t, "POST", ts.URL+"/api/inputs", `{"a": 1, "padding": "`+strings.Repeat(" ", maxRequestBody)+`"}`, http.StatusRequestEntityTooLarge, nil)
+	request(t, "POST", ts.URL+"/api/inputs", `{"a": 2}`, http.StatusBadRequest, nil)
+	request(t, "POST", ts.URL+"/api/inputs", `[1]`, http.StatusBadRequest, nil)
+
+	var steps []stepResult
+	request(t, "POST", ts.URL+"/api/step?count=2", "", http.StatusOK, &steps)
+	if len(steps) != 2 || steps[1].Step != 1 || steps[1].Outputs["sum"] != "1" || steps[1].Outputs["carry"] != "0" {
+		t.Errorf("unexpected steps %+v", steps)
+	}
+	request(t, "POST", ts.URL+"/api/step?count=0", "", http.StatusBadRequest, nil)
+	request(t, "GET", ts.URL+"/api/step", "", http.StatusMethodNotAllowed, nil)
+
+	var nodes []nodeInfo
+	request(t, "GET", ts.URL+"/api/nodes", "", http.StatusOK, &nodes)
+	states := map[string]string{}
+	for _, node := range nodes {
+		states[node.Name] = node.State
+	}
+	expected := map[string]string{"a": "1", "b": "0", "sum": "1", "carry": "0"}
+	for name, state := range expected {
+		if states[name] != state {
+			t.Errorf("expected node %s to be %s but got %s", name, state, states[name])
+		}
+	}
+}
+
+// Connects to the event stream with a handshake written by hand
+func dialEvents(t *testing.T, ts *httptest.Server) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	io.WriteString(conn, "GET /api/events HTTP/1.1\r\n"+
+		"Host: "+ts.Listener.Addr().String()+"\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: "+key+"\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// accept key of the sample handshake in RFC 6455
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake response %s %v", resp.Status, resp.Header)
+	}
+	return conn, reader
+}
+
+func readTextFrame(t *testing.T, reader *bufio.Reader) string {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		t.Fatal(err)
+	}
+	if header[0] != finalBit|opText || header[1]&maskBit != 0 || header[1] >= 126 {
+		t.Fatalf("unexpected frame header %x", header)
+	}
+	payload := make([]byte, header[1])
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatal(err)
+	}
+	return string(payload)
+}
+
+func TestServerStreamsNodeChanges(t *testing.T) {
+	ts := newTestServer(t)
+	conn, reader := dialEvents(t, ts)
+
+	request(t, "POST", ts.URL+"/api/inputs", `{"a": 1, "b": 1}`, http.StatusOK, nil)
+	request(t, "POST", ts.URL+"/api/step", "", http.StatusOK, nil)
+	for _, change := range []NodeChange{
+		{Step: 0, Node: "a", From: "x", To: "1"},
+		{Step: 0, Node: "a0[0]", From: "x", To: "1"},
+		{Step: 0, Node: "b", From: "x", To: "1"},
+		{Step: 0, Node: "carry", From: "x", To: "1"},
+		// the supply nets of the netlist are driven on the first step
+		{Step: 0, Node: "gnd", From: "x", To: "0"},
+		{Step: 0, Node: "sum", From: "x", To: "0"},
+		{Step: 0, Node: "vdd", From: "x", To: "1"},
+		{Step: 0, Node: "x0[0]", From: "x", To: "0"},
+	} {
+		var actual NodeChange
+		if err := json.Unmarshal([]byte(readTextFrame(t, reader)), &actual); err != nil {
+			t.Fatal(err)
+		}
+		if actual != change {
+			t.Errorf("expected change %+v but got %+v", change, actual)
+		}
+	}
+
+	// unchanged nodes are not sent again
+	request(t, "POST", ts.URL+"/api/inputs", `{"b": 0}`, http.StatusOK, nil)
+	request(t, "POST", ts.URL+"/api/step", "", http.StatusOK, nil)
+	for _, change := range []string{
+		`{"step":1,"node":"a0[0]","from":"1","to":"0"}`,
+		`{"step":1,"node":"b","from":"1","to":"0"}`,
+		`{"step":1,"node":"carry","from":"1","to":"0"}`,
+		`{"step":1,"node":"sum","from":"0","to":"1"}`,
+		`{"step":1,"node":"x0[0]","from":"0","to":"1"}`,
+	} {
+		if actual := readTextFrame(t, reader); actual != change {
+			t.Errorf("expected change %s but got %s", change, actual)
+		}
+	}
+
+	// masked close frame, answered with a close frame
+	conn.Write([]byte{finalBit | opClose, maskBit, 1, 2, 3, 4})
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil || header[0] != finalBit|opClose {
+		t.Errorf("expected a close frame but got %x, %v", header, err)
+	}
+}
+
+func TestServerDropsSlowClients(t *testing.T) {
+	netlist, err := sim.LoadDesign(testHalfAdderDesign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(netlist)
+	// a client that never reads its queue of a single message
+	client := make(chan []byte, 1)
+	s.clients[client] = true
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	request(t, "POST", ts.URL+"/api/step", "", http.StatusOK, nil)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.clients[client] {
+		t.Errorf("expected the slow client to be dropped")
+	}
+	if _, ok := <-client; !ok {
+		t.Errorf("expected the first change to be queued")
+	}
+	if _, ok := <-client; ok {
+		t.Errorf("expected the queue of the dropped client to be closed")
+	}
+}
+
+func TestServerRejectsPlainEventRequests(t *testing.T) {
+	ts := newTestServer(t)
+	request(t, "GET", ts.URL+"/api/events", "", http.StatusBadRequest, nil)
+}
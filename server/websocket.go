@@ -0,0 +1,206 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Minimal RFC 6455 server side, enough to push text messages to clients
+// and answer their pings and close frames
+
+const (
+	opText   = 0x1
+	opClose  = 0x8
+	opPing   = 0x9
+	opPong   = 0xA
+	finalBit = 0x80
+	maskBit  = 0x80
+
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	// Largest client frame read, clients only send control frames
+	maxClientPayload = 1 << 16
+	writeTimeout     = 5 * time.Second
+)
+
+type wsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// Serializes the frames written by the server and the replies to the
+	// control frames of the client
+	writeMu sync.Mutex
+	closed  bool
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func acceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// Upgrades the request to a WebSocket connection, replying with an HTTP
+// error if it is not a valid handshake
+func acceptWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		key == "" {
+		http.Error(w, "expected a websocket handshake", http.StatusBadRequest)
+		return nil, errors.New("not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported websocket version")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection cannot be upgraded", http.StatusInternalServerError)
+		return nil, errors.New("response writer cannot be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := io.WriteString(conn, response); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, reader: rw.Reader}, nil
+}
+
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+
+	header := []byte{finalBit | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	if opcode == opClose {
+		c.closed = true
+	}
+	return nil
+}
+
+func (c *wsConn) WriteText(message []byte) error {
+	return c.writeFrame(opText, message)
+}
+
+func (c *wsConn) readFrame() (opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.reader, header[:]); err != nil {
+		return
+	}
+	opcode = header[0] & 0x0F
+	if header[1]&maskBit == 0 {
+		return 0, nil, errors.New("client frames must be masked")
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err = io.ReadFull(c.reader, extended[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err = io.ReadFull(c.reader, extended[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > maxClientPayload {
+		return 0, nil, fmt.Errorf("client frame of %d bytes is too large", length)
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.reader, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// Writes the messages of queue until it is closed or a write fails, then
+// closes the connection
+func (c *wsConn) writeMessages(queue <-chan []byte) {
+	defer c.Close()
+	for message := range queue {
+		if err := c.WriteText(message); err != nil {
+			return
+		}
+	}
+}
+
+// Reads client frames until the connection closes, answering pings and
+// discarding any message
+func (c *wsConn) discardMessages() {
+	defer c.Close()
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case opPing:
+			if c.writeFrame(opPong, payload) != nil {
+				return
+			}
+		case opClose:
+			c.writeFrame(opClose, payload)
+			return
+		}
+	}
+}
+
+func (c *wsConn) Close() error {
+	c.writeMu.Lock()
+	c.closed = true
+	c.writeMu.Unlock()
+	return c.conn.Close()
+}
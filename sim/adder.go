@@ -1,4 +1,4 @@
-package main
+package sim
 
 func NewSimpleAdder(input1, input2 *Node) (out, carry *Node, adder *CustomComponent) {
 	out, xorGate := NewXorGate(input1, input2)
@@ -7,6 +7,7 @@ func NewSimpleAdder(input1, input2 *Node) (out, carry *Node, adder *CustomCompon
 		"SimpleAdder",
 		[]Component{xorGate, andGate},
 		[]*Node{input1, input2},
+		[]*Node{out, carry},
 	)
 	return
 }
@@ -34,6 +35,7 @@ func NewFullAdder(input1, input2, carryIn *Node) (out, carry *Node, adder *Custo
 			andGate,
 		},
 		[]*Node{input1, input2, carryIn},
+		[]*Node{out, carry},
 	)
 	return
 }
@@ -45,6 +47,7 @@ func NewAdderSubtractor(input1, input2, carryIn, operation *Node) (out, carry *N
 		"AdderSubtractor",
 		[]Component{xorGate, adder},
 		[]*Node{input1, input2, carryIn, operation},
+		[]*Node{out, carry},
 	)
 	return
 }
@@ -1,4 +1,4 @@
-package main
+package sim
 
 import "testing"
 
@@ -19,8 +19,8 @@ func TestSimpleAdder(t *testing.T) {
 		input1 := NewNode("Input1")
 		input2 := NewNode("Input2")
 		inputComponents := []Component{
-			NewInput(input1, tc.input1),
-			NewInput(input2, tc.input2),
+			NewInput("input1", input1, tc.input1),
+			NewInput("input2", input2, tc.input2),
 		}
 		components = append(components, inputComponents...)
 
@@ -29,7 +29,7 @@ func TestSimpleAdder(t *testing.T) {
 
 		c := NewCircuit(components, 4, false)
 		if err := c.Tick(); err != nil {
-			t.Errorf(err.Error())
+			t.Error(err)
 		}
 		if adderOut.State != tc.expectedOut || adderCarry.State != tc.expectedCarry {
 			t.Errorf("Inputs<input1: %s, input2: %s> generated output state <out: %s, carry: %s> instead of <out: %s, carry: %s>",
@@ -61,9 +61,9 @@ func TestFullAdder(t *testing.T) {
 		input2 := NewNode("Input2")
 		carryIn := NewNode("CarryIn")
 		inputComponents := []Component{
-			NewInput(input1, tc.input1),
-			NewInput(input2, tc.input2),
-			NewInput(carryIn, tc.carryIn),
+			NewInput("input1", input1, tc.input1),
+			NewInput("input2", input2, tc.input2),
+			NewInput("carryIn", carryIn, tc.carryIn),
 		}
 		components = append(components, inputComponents...)
 
@@ -72,7 +72,7 @@ func TestFullAdder(t *testing.T) {
 
 		c := NewCircuit(components, 4, false)
 		if err := c.Tick(); err != nil {
-			t.Errorf(err.Error())
+			t.Error(err)
 		}
 		if adderOut.State != tc.expectedOut || adderCarry.State != tc.expectedCarry {
 			t.Errorf("Inputs<input1: %s, input2: %s> generated output state <out: %s, carry: %s> instead of <out: %s, carry: %s>",
@@ -116,10 +116,10 @@ func TestAdderSubtractor(t *testing.T) {
 		carryIn := NewNode("CarryIn")
 		operation := NewNode("Operation")
 		inputComponents := []Component{
-			NewInput(input1, tc.input1),
-			NewInput(input2, tc.input2),
-			NewInput(carryIn, tc.carryIn),
-			NewInput(operation, tc.operation),
+			NewInput("input1", input1, tc.input1),
+			NewInput("input2", input2, tc.input2),
+			NewInput("carryIn", carryIn, tc.carryIn),
+			NewInput("operation", operation, tc.operation),
 		}
 		components = append(components, inputComponents...)
 
@@ -128,7 +128,7 @@ func TestAdderSubtractor(t *testing.T) {
 
 		c := NewCircuit(components, 4, false)
 		if err := c.Tick(); err != nil {
-			t.Errorf(err.Error())
+			t.Error(err)
 		}
 		if adderOut.State != tc.expectedOut || adderCarry.State != tc.expectedCarry {
 			t.Errorf("Inputs<input1: %s, input2: %s, carryIn: %s, operation: %s> generated output state <out: %s, carry: %s> instead of <out: %s, carry: %s>",
@@ -0,0 +1,209 @@
+package sim
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Computes the output states of a behavioral component from its input states.
+// Inputs are guaranteed to be defined when the function is called
+type LogicFunction func(inputs []NodeState) []NodeState
+
+// Component whose outputs are computed by a Go function instead of being
+// simulated at transistor level
+type FunctionComponent struct {
+	ComponentType string
+	Inputs        []*Node
+	Outputs       []*Node
+	Function      LogicFunction
+}
+
+func NewFunctionComponent(componentType string, inputs, outputs []*Node, function LogicFunction) *FunctionComponent {
+	return &FunctionComponent{
+		ComponentType: componentType,
+		Inputs:        inputs,
+		Outputs:       outputs,
+		Function:      function,
+	}
+}
+
+func (f *FunctionComponent) Reset() {
+	for _, output := range f.Outputs {
+		output.Reset()
+	}
+}
+
+func (f *FunctionComponent) Ready() bool {
+	for _, input := range f.Inputs {
+		if input.State == Undefined {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *FunctionComponent) Act() error {
+	if !f.Ready() {
+		return fmt.Errorf("component %s was executed before it was ready", f.Debug())
+	}
+	inputs := make([]NodeState, len(f.Inputs))
+	for i, input := range f.Inputs {
+		inputs[i] = input.State
+	}
+	outputs := f.Function(inputs)
+	if len(outputs) != len(f.Outputs) {
+		return fmt.Errorf("component %s produced %d outputs but has %d output nodes",
+			f.ComponentType, len(outputs), len(f.Outputs))
+	}
+	for i, output := range f.Outputs {
+		if err := output.Change(outputs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FunctionComponent) GetID() ComponentID {
+	return ComponentID{}
+}
+
+func (f *FunctionComponent) GetPosition() (int32, int32) {
+	return 0, 0
+}
+
+func (f *FunctionComponent) Nodes() []*Node {
+	return append(append([]*Node{}, f.Inputs...), f.Outputs...)
+}
+
+func (f *FunctionComponent) Clone(overrides ComponentID) Component {
+	// TODO
+	return f
+}
+
+func (f *FunctionComponent) Debug() string {
+	var builder strings.Builder
+	builder.WriteString(f.ComponentType + "<inputs: ")
+	for i, input := range f.Inputs {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(input.Debug())
+	}
+	builder.WriteString(" | outputs: ")
+	for i, output := range f.Outputs {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(output.Debug())
+	}
+	builder.WriteString(">")
+	return builder.String()
+}
+
+func boolToState(b bool) NodeState {
+	if b {
+		return On
+	}
+	return Off
+}
+
+func newBehavioralGate(componentType string, inputs []*Node, function func(inputs []bool) bool) (*Node, *FunctionComponent) {
+	outputNode := NewNode(fmt.Sprintf("%s-Output", componentType))
+	return outputNode, NewFunctionComponent(
+		componentType,
+		inputs,
+		[]*Node{outputNode},
+		func(states []NodeState) []NodeState {
+			values := make([]bool, len(states))
+			for i, state := range states {
+				values[i] = state == On
+			}
+			return []NodeState{boolToState(function(values))}
+		},
+	)
+}
+
+// Behavioral equivalent of NewNotGate
+func NewBehavioralNotGate(input *Node) (*Node, *FunctionComponent) {
+	return newBehavioralGate("NotGate", []*Node{input}, func(in []bool) bool {
+		return !in[0]
+	})
+}
+
+// Behavioral equivalent of NewAndGate
+func NewBehavioralAndGate(input1, input2 *Node) (*Node, *FunctionComponent) {
+	return newBehavioralGate("AndGate", []*Node{input1, input2}, func(in []bool) bool {
+		return in[0] && in[1]
+	})
+}
+
+// Behavioral equivalent of NewOrGate
+func NewBehavioralOrGate(input1, input2 *Node) (*Node, *FunctionComponent) {
+	return newBehavioralGate("OrGate", []*Node{input1, input2}, func(in []bool) bool {
+		return in[0] || in[1]
+	})
+}
+
+// Behavioral equivalent of NewNandGate
+func NewBehavioralNandGate(input1, input2 *Node) (*Node, *FunctionComponent) {
+	return newBehavioralGate("NandGate", []*Node{input1, input2}, func(in []bool) bool {
+		return !(in[0] && in[1])
+	})
+}
+
+// Behavioral equivalent of NewXorGate
+func NewBehavioralXorGate(input1, input2 *Node) (*Node, *FunctionComponent) {
+	return newBehavioralGate("XorGate", []*Node{input1, input2}, func(in []bool) bool {
+		return in[0] != in[1]
+	})
+}
+
+// sums the given bits, returning the result and carry states
+func addBits(bits ...bool) []NodeState {
+	sum := 0
+	for _, bit := range bits {
+		if bit {
+			sum++
+		}
+	}
+	return []NodeState{boolToState(sum%2 == 1), boolToState(sum > 1)}
+}
+
+func newBehavioralAdder(componentType string, inputs []*Node, function func(in []bool) []NodeState) (out, carry *Node, adder *FunctionComponent) {
+	out = NewNode(fmt.Sprintf("%s-Output", componentType))
+	carry = NewNode(fmt.Sprintf("%s-Carry", componentType))
+	adder = NewFunctionComponent(
+		componentType,
+		inputs,
+		[]*Node{out, carry},
+		func(states []NodeState) []NodeState {
+			values := make([]bool, len(states))
+			for i, state := range states {
+				values[i] = state == On
+			}
+			return function(values)
+		},
+	)
+	return
+}
+
+// Behavioral equivalent of NewSimpleAdder
+func NewBehavioralSimpleAdder(input1, input2 *Node) (out, carry *Node, adder *FunctionComponent) {
+	return newBehavioralAdder("SimpleAdder", []*Node{input1, input2}, func(in []bool) []NodeState {
+		return addBits(in[0], in[1])
+	})
+}
+
+// Behavioral equivalent of NewFullAdder
+func NewBehavioralFullAdder(input1, input2, carryIn *Node) (out, carry *Node, adder *FunctionComponent) {
+	return newBehavioralAdder("FullAdder", []*Node{input1, input2, carryIn}, func(in []bool) []NodeState {
+		return addBits(in[0], in[1], in[2])
+	})
+}
+
+// Behavioral equivalent of NewAdderSubtractor
+func NewBehavioralAdderSubtractor(input1, input2, carryIn, operation *Node) (out, carry *Node, component *FunctionComponent) {
+	return newBehavioralAdder("AdderSubtractor", []*Node{input1, input2, carryIn, operation}, func(in []bool) []NodeState {
+		return addBits(in[0], in[1] != in[3], in[2])
+	})
+}
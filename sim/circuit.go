@@ -0,0 +1,239 @@
+package sim
+
+import (
+	"fmt"
+	"slices"
+)
+
+var MAX_DEFERS = 10
+
+type Circuit struct {
+	tracer     *Tracer
+	maxDefers  int
+	terminals  []Component
+	components []Component
+	meters     []Component
+
+	steps     uint64
+	recorders []*VCDRecorder
+}
+
+// Builds a circuit traced by DefaultTracer, debug circuits also trace the
+// components acting
+func NewCircuit(components []Component, maxDefers int, debug bool) *Circuit {
+	tracer := DefaultTracer
+	if debug && tracer.Level < TraceDebug {
+		tracer = tracer.WithLevel(TraceDebug)
+	}
+	circuit := &Circuit{
+		tracer:    tracer,
+		maxDefers: maxDefers,
+	}
+	circuit.AddComponents(append(BaseComponents, components...)...)
+	return circuit
+}
+
+// Returns every component of the circuit, terminals first and meters last
+func (c *Circuit) Components() []Component {
+	components := append([]Component{}, c.terminals...)
+	components = append(components, c.components...)
+	return append(components, c.meters...)
+}
+
+func (c *Circuit) addComponent(component Component) {
+	switch component.(type) {
+	case *Terminal:
+		c.terminals = append(c.terminals, component)
+	case *Meter:
+		c.meters = append(c.meters, component)
+	default:
+		c.components = append(c.components, component)
+	}
+	c.tracer.Emit(TraceEvent{Step: c.steps, Level: TraceDebug, Kind: TraceComponentAdded, Component: ComponentName(component)})
+}
+
+// Replaces the tracer the circuit reports its steps to
+func (c *Circuit) SetTracer(tracer *Tracer) {
+	c.tracer = tracer
+}
+
+func (c *Circuit) AddComponents(components ...Component) {
+	for _, component := range components {
+		c.addComponent(component)
+	}
+}
+
+// Collects the nodes of the components, their subcomponents and the nets
+// connecting them. The walk stops at the shared supply nodes, which join the
+// blocks of every circuit ever built
+func collectNodes(components []Component, seen map[*Node]bool, nodes []*Node) []*Node {
+	for _, component := range components {
+		pending := slices.Clone(component.Nodes())
+		for len(pending) > 0 {
+			node := pending[0]
+			pending = pending[1:]
+			if seen[node] {
+				continue
+			}
+			seen[node] = true
+			nodes = append(nodes, node)
+			if node != SharedSourceNode && node != SharedGroundNode {
+				pending = append(pending, node.connections...)
+			}
+		}
+		nodes = collectNodes(Subcomponents(component), seen, nodes)
+	}
+	return nodes
+}
+
+// Every node of the circuit, in a stable order
+func (c *Circuit) nodes() []*Node {
+	return collectNodes(c.Components(), map[*Node]bool{}, nil)
+}
+
+// Sets every node in the circuit back to Undefined and powers its feedback
+// loops on again, forgetting the states they held
+func (c *Circuit) Reset() {
+	for _, node := range c.nodes() {
+		node.held = Off
+		if !node.forced {
+			node.State = Undefined
+		}
+	}
+}
+
+// Sets every node in the circuit back to Undefined for a new step, each
+// remembering the state it held on the previous one
+func (c *Circuit) clear() {
+	for _, node := range c.nodes() {
+		if node.forced {
+			continue
+		}
+		if node.State != Undefined {
+			node.held = node.State
+		}
+		node.State = Undefined
+	}
+}
+
+// Starts a step, its components reporting to the circuit tracer through the
+// returned run
+func (c *Circuit) begin() *run {
+	c.clear()
+	return newRun(c.tracer, c.steps, c.nodes)
+}
+
+// Drives the nodes of the terminal
+func drive(terminal Component, r *run) error {
+	err := terminal.Act()
+	r.traceChanges()
+	return err
+}
+
+// Ends a step once every component acted, reading the meters and toggling
+// the clocks
+func (c *Circuit) finish(r *run) error {
+	latchComponents(c.components)
+	latchComponents(c.terminals)
+	for _, meter := range c.meters {
+		if err := meter.Act(); err != nil {
+			return err
+		}
+		node := meter.(*Meter).Node
+		level := TraceInfo
+		if node.State == Undefined {
+			level = TraceWarn
+		}
+		r.trace(level, TraceEvent{Kind: TraceMeterRead, Component: ComponentName(meter), Node: node.ID, To: node.State})
+	}
+	for _, recorder := range c.recorders {
+		recorder.Sample(c.steps)
+	}
+	c.steps++
+	return nil
+}
+
+// Runs a step of the circuit. A step whose feedback loops were seeded with
+// states other than the ones their drivers settle on is run again, starting
+// from the settled states
+func (c *Circuit) Tick() error {
+	for range c.maxDefers {
+		r := c.begin()
+		for _, terminal := range c.terminals {
+			if err := drive(terminal, r); err != nil {
+				return r.traceConflict(err)
+			}
+		}
+		if err := actComponents(c.components, c.maxDefers, r); err != nil {
+			return r.traceConflict(err)
+		}
+		if !r.unsettled {
+			return c.finish(r)
+		}
+	}
+	return c.unsettledError()
+}
+
+func (c *Circuit) unsettledError() error {
+	return fmt.Errorf("circuit did not settle after running step %d %d times", c.steps, c.maxDefers)
+}
+
+// Number of steps run since the circuit was built
+func (c *Circuit) Steps() uint64 {
+	return c.steps
+}
+
+// Samples the recorder after every step of the circuit
+func (c *Circuit) Record(recorder *VCDRecorder) {
+	c.recorders = append(c.recorders, recorder)
+}
+
+// Component holding state between steps, sampling its inputs once every
+// other component has settled
+type Sequential interface {
+	Latch()
+}
+
+func latchComponents(components []Component) {
+	for _, component := range components {
+		if sequential, ok := component.(Sequential); ok {
+			sequential.Latch()
+		}
+		latchComponents(Subcomponents(component))
+	}
+}
+
+func collectInstances(components []Component) (instances []*BlockInstance) {
+	for _, component := range components {
+		switch component := component.(type) {
+		case *BlockInstance:
+			instances = append(instances, component)
+		case *CustomComponent:
+			instances = append(instances, collectInstances(component.Subcomponents)...)
+		}
+	}
+	return
+}
+
+// Returns every block instance in the circuit, including the ones nested
+// inside custom components
+func (c *Circuit) Instances() []*BlockInstance {
+	return collectInstances(c.components)
+}
+
+// Selects the abstraction level used to simulate the instance with the given name
+func (c *Circuit) SetLevel(name string, level AbstractionLevel) error {
+	for _, instance := range c.Instances() {
+		if instance.Name == name {
+			instance.SetLevel(level)
+			return nil
+		}
+	}
+	return fmt.Errorf("no block instance named %s", name)
+}
+
+func (c *Circuit) SetAllLevels(level AbstractionLevel) {
+	for _, instance := range c.Instances() {
+		instance.SetLevel(level)
+	}
+}
@@ -1,13 +1,11 @@
 // TODO: extract common fields to a separate struct and act on it
 // to remove code duplication
 // TODO: share NewX and NewXFromNodes
-package main
+package sim
 
 import (
 	"fmt"
 	"strings"
-
-	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 type ComponentType int
@@ -21,8 +19,8 @@ const (
 )
 
 type Position struct {
-	X int32
-	Y int32
+	X int32 `json:"x"`
+	Y int32 `json:"y"`
 }
 
 func (p Position) Unpack() (int32, int32) {
@@ -34,6 +32,23 @@ type ComponentID struct {
 	Name string
 	ID   string
 	Position
+	Orientation
+}
+
+// Moves the component, promoted to every component embedding its ID
+func (id *ComponentID) SetPosition(p Position) {
+	id.Position = p
+}
+
+func (id *ComponentID) SetOrientation(o Orientation) {
+	id.Orientation = o
+}
+
+// Component placed on a schematic that can be moved and oriented
+type Movable interface {
+	Component
+	SetPosition(Position)
+	SetOrientation(Orientation)
 }
 
 type Component interface {
@@ -44,8 +59,6 @@ type Component interface {
 	// propagates component input to its outputs, should only be called if c.Ready() returns true
 	Act() error
 
-	Render(DrawingState)
-
 	GetID() ComponentID
 	// Equivalent of GetID().Position.Unpack()
 	GetPosition() (int32, int32)
@@ -53,21 +66,47 @@ type Component interface {
 	// TODO: make components own a []*Node list to avoid multiple instantiations per render cycle
 	Nodes() []*Node
 
-	// TODO: make clone empty node connections
+	// Copies the component with unconnected nodes
 	Clone(overrides ComponentID) Component
 
 	Debug() string
 }
 
+// Clones the components keeping the connections between them, connections
+// to components outside the group are left out. id gives the ID of the clone
+// of each component, called in order
+func CloneGroup(components []Component, id func(Component) ComponentID) []Component {
+	clones := make([]Component, len(components))
+	cloneOf := map[*Node]*Node{}
+	for i, component := range components {
+		clones[i] = component.Clone(id(component))
+		cloneNodes := clones[i].Nodes()
+		for j, node := range component.Nodes() {
+			cloneOf[node] = cloneNodes[j]
+		}
+	}
+	connected := map[[2]*Node]bool{}
+	for _, component := range components {
+		for _, node := range component.Nodes() {
+			for _, other := range node.connections {
+				otherClone, ok := cloneOf[other]
+				if !ok || connected[[2]*Node{other, node}] {
+					continue
+				}
+				connected[[2]*Node{node, other}] = true
+				cloneOf[node].Connect(otherClone)
+			}
+		}
+	}
+	return clones
+}
+
 // TODO: make terminalType an enum
 type Terminal struct {
 	ComponentID
 	Node         *Node
 	state        NodeState
 	terminalType string
-
-	// Rendering data
-	resource rl.Texture2D
 }
 
 func NewTerminal(
@@ -90,13 +129,11 @@ func NewDrawableTerminal(
 	node *Node,
 	state NodeState,
 	terminalType string,
-	resourceName string,
 ) *Terminal {
 	t := &Terminal{Node: node, state: state, terminalType: terminalType}
 	t.Node.Parent = t
 
 	t.ComponentID.Name = name
-	t.resource = loadGridTexture(resourceName)
 	return t
 }
 
@@ -104,28 +141,37 @@ func NewSource(name string, node *Node) *Terminal {
 	return NewTerminal(name, node, On, "Source")
 }
 
-func NewDrawableSource(name string, node *Node, resourceName string) *Terminal {
-	return NewDrawableTerminal(name, node, On, "Source", resourceName)
+func NewDrawableSource(name string, node *Node) *Terminal {
+	return NewDrawableTerminal(name, node, On, "Source")
 }
 
 func NewGround(name string, node *Node) *Terminal {
 	return NewTerminal(name, node, Off, "Ground")
 }
 
-func NewDrawableGround(name string, node *Node, resourceName string) *Terminal {
-	return NewDrawableTerminal(name, node, Off, "Ground", resourceName)
+func NewDrawableGround(name string, node *Node) *Terminal {
+	return NewDrawableTerminal(name, node, Off, "Ground")
 }
 
 func NewInput(name string, node *Node, state NodeState) *Terminal {
 	return NewTerminal(name, node, state, "Input")
 }
 
-func NewDrawableInput(name string, node *Node, state NodeState, resourceName string) *Terminal {
-	return NewDrawableTerminal(name, node, state, "Input", resourceName)
+func NewDrawableInput(name string, node *Node, state NodeState) *Terminal {
+	return NewDrawableTerminal(name, node, state, "Input")
+}
+
+// Input toggled after every step, starting off
+func NewClock(name string, node *Node) *Terminal {
+	return NewTerminal(name, node, Off, "Clock")
+}
+
+func NewDrawableClock(name string, node *Node) *Terminal {
+	return NewDrawableTerminal(name, node, Off, "Clock")
 }
 
 func (t *Terminal) Reset() {
-	t.Node.State = Undefined
+	t.Node.Reset()
 }
 
 func (t *Terminal) Ready() bool {
@@ -136,16 +182,30 @@ func (t *Terminal) Act() error {
 	return t.Node.Change(t.state)
 }
 
-func (t *Terminal) Render(s DrawingState) {
-	x, y := t.Position.Unpack()
-	rl.DrawTexture(t.resource, x, y, rl.White)
-	rl.DrawText(t.Name, x, y+gridComponentImageSize, gridComponentFontSize, rl.White)
-
-	if s.state == StateComponentSelected && *s.selectedComponent == t {
-		drawComponentOutline(*s.selectedComponent, rl.Yellow)
-		x, y := (*s.selectedComponent).GetPosition()
-		rl.DrawRectangleLines(x, y, gridComponentImageSize, gridComponentImageSize, rl.Yellow)
+// Toggles clocks once the step has settled
+func (t *Terminal) Latch() {
+	if t.terminalType != "Clock" {
+		return
 	}
+	if t.state == On {
+		t.state = Off
+	} else {
+		t.state = On
+	}
+}
+
+// Kind of terminal, Source, Ground, Input or Clock
+func (t *Terminal) Type() string {
+	return t.terminalType
+}
+
+// State driven on the next step
+func (t *Terminal) State() NodeState {
+	return t.state
+}
+
+func (t *Terminal) SetState(state NodeState) {
+	t.state = state
 }
 
 func (t *Terminal) GetID() ComponentID {
@@ -161,14 +221,9 @@ func (t *Terminal) Nodes() []*Node {
 }
 
 func (t Terminal) Clone(newID ComponentID) Component {
-	nodeCopy := *t.Node
-
 	newTerminal := t
 	newTerminal.ComponentID = newID
-
-	newTerminal.Node = &nodeCopy
-	newTerminal.Node.Parent = &newTerminal
-
+	newTerminal.Node = t.Node.clone(&newTerminal)
 	return &newTerminal
 }
 
@@ -179,9 +234,6 @@ func (t *Terminal) Debug() string {
 type Meter struct {
 	ComponentID
 	Node *Node
-
-	// Rendering data
-	resource rl.Texture2D
 }
 
 func NewMultimeter(name string, node *Node) *Meter {
@@ -192,18 +244,16 @@ func NewMultimeter(name string, node *Node) *Meter {
 	return m
 }
 
-func NewDrawableMultimeter(name string, node *Node, resourceName string) *Meter {
+func NewDrawableMultimeter(name string, node *Node) *Meter {
 	m := &Meter{Node: node}
 	m.Node.Parent = m
 
 	m.ComponentID.Name = name
-	m.resource = loadGridTexture(resourceName)
-
 	return m
 }
 
 func (m *Meter) Reset() {
-	m.Node.State = Undefined
+	m.Node.Reset()
 }
 
 func (m *Meter) Ready() bool {
@@ -211,10 +261,6 @@ func (m *Meter) Ready() bool {
 }
 
 func (m *Meter) Act() error {
-	if m.Node.State == Undefined {
-		fmt.Println("WARN: acting on ", m.Debug(), " in undefined state")
-	}
-	fmt.Println(m.Debug())
 	return nil
 }
 
@@ -223,29 +269,12 @@ func (m *Meter) Nodes() []*Node {
 }
 
 func (m Meter) Clone(newID ComponentID) Component {
-	nodeCopy := *m.Node
-
 	newMeter := m
 	newMeter.ComponentID = newID
-
-	newMeter.Node = &nodeCopy
-	newMeter.Node.Parent = &newMeter
+	newMeter.Node = m.Node.clone(&newMeter)
 	return &newMeter
 }
 
-func (m *Meter) Render(s DrawingState) {
-	x, y := m.GetPosition()
-	rl.DrawTexture(m.resource, x, y, rl.White)
-	rl.DrawText(m.Name, x, y+gridComponentImageSize, gridComponentFontSize, rl.White)
-
-	if s.state == StateComponentSelected && *s.selectedComponent == m {
-		drawComponentOutline(*s.selectedComponent, rl.Yellow)
-		x, y := (*s.selectedComponent).GetPosition()
-		rl.DrawRectangleLines(x, y, gridComponentImageSize, gridComponentImageSize, rl.Yellow)
-
-	}
-}
-
 func (m *Meter) GetID() ComponentID {
 	return m.ComponentID
 }
@@ -262,10 +291,6 @@ type Resistor struct {
 	ComponentID
 	Node1 *Node
 	Node2 *Node
-
-	// Rendering data
-	idleResource     rl.Texture2D
-	selectedResource rl.Texture2D
 }
 
 func NewResistor(name string, node1, node2 *Node) *Resistor {
@@ -278,12 +303,7 @@ func NewResistor(name string, node1, node2 *Node) *Resistor {
 	return r
 }
 
-func NewDrawableResistor(
-	name string,
-	node1, node2 *Node,
-	idleResourceName,
-	selectedResourceName string,
-) *Resistor {
+func NewDrawableResistor(name string, node1, node2 *Node) *Resistor {
 	r := &Resistor{
 		Node1: node1,
 		Node2: node2,
@@ -292,14 +312,12 @@ func NewDrawableResistor(
 	r.Node2.Parent = r
 
 	r.ComponentID.Name = name
-	r.idleResource = loadGridTexture(idleResourceName)
-	r.selectedResource = loadGridTexture(selectedResourceName)
 	return r
 }
 
 func (r *Resistor) Reset() {
-	r.Node1.State = Undefined
-	r.Node2.State = Undefined
+	r.Node1.Reset()
+	r.Node2.Reset()
 }
 
 func (r *Resistor) Ready() bool {
@@ -324,29 +342,13 @@ func (r *Resistor) Nodes() []*Node {
 }
 
 func (r Resistor) Clone(newID ComponentID) Component {
-	node1Copy := *r.Node1
-	node2Copy := *r.Node2
-
 	newResistor := r
 	newResistor.ComponentID = newID
-
-	newResistor.Node1 = &node1Copy
-	newResistor.Node2 = &node2Copy
-	newResistor.Node1.Parent = &newResistor
-	newResistor.Node2.Parent = &newResistor
+	newResistor.Node1 = r.Node1.clone(&newResistor)
+	newResistor.Node2 = r.Node2.clone(&newResistor)
 	return &newResistor
 }
 
-func (r *Resistor) Render(s DrawingState) {
-	x, y := r.GetPosition()
-	rl.DrawTexture(r.idleResource, x, y, rl.White)
-	rl.DrawText(r.Name, x, y+gridComponentImageSize, gridComponentFontSize, rl.White)
-
-	if s.state == StateComponentSelected && *s.selectedComponent == r {
-		rl.DrawTexture(r.selectedResource, x, y, rl.White)
-	}
-}
-
 func (r *Resistor) GetID() ComponentID {
 	return r.ComponentID
 }
@@ -364,9 +366,6 @@ type Transistor struct {
 	Source *Node
 	Drain  *Node
 	Gate   *Node
-
-	// Rendering data
-	resource rl.Texture2D
 }
 
 func NewTransistor(name string, source, gate, drain *Node) *Transistor {
@@ -381,22 +380,20 @@ func NewTransistor(name string, source, gate, drain *Node) *Transistor {
 	return t
 }
 
-func NewDrawableTransistor(name string, source, gate, drain *Node, resourceName string) *Transistor {
+func NewDrawableTransistor(name string, source, gate, drain *Node) *Transistor {
 	t := &Transistor{Source: source, Drain: drain, Gate: gate}
 	t.Source.Parent = t
 	t.Drain.Parent = t
 	t.Gate.Parent = t
 
 	t.ComponentID.Name = name
-	t.resource = loadGridTexture(resourceName)
-
 	return t
 }
 
 func (t *Transistor) Reset() {
-	t.Source.State = Undefined
-	t.Drain.State = Undefined
-	t.Gate.State = Undefined
+	t.Source.Reset()
+	t.Drain.Reset()
+	t.Gate.Reset()
 }
 
 func (t *Transistor) Ready() bool {
@@ -430,36 +427,14 @@ func (t *Transistor) Nodes() []*Node {
 }
 
 func (t Transistor) Clone(newID ComponentID) Component {
-	sourceCopy := *t.Source
-	gateCopy := *t.Gate
-	drainCopy := *t.Drain
-
 	newTransistor := t
 	newTransistor.ComponentID = newID
-
-	newTransistor.Source = &sourceCopy
-	newTransistor.Gate = &gateCopy
-	newTransistor.Drain = &drainCopy
-
-	newTransistor.Source.Parent = &newTransistor
-	newTransistor.Gate.Parent = &newTransistor
-	newTransistor.Drain.Parent = &newTransistor
-
+	newTransistor.Source = t.Source.clone(&newTransistor)
+	newTransistor.Gate = t.Gate.clone(&newTransistor)
+	newTransistor.Drain = t.Drain.clone(&newTransistor)
 	return &newTransistor
 }
 
-func (t *Transistor) Render(s DrawingState) {
-	x, y := t.GetPosition()
-	rl.DrawTexture(t.resource, x, y, rl.White)
-	rl.DrawText(t.Name, x, y+gridComponentImageSize, gridComponentFontSize, rl.White)
-
-	if s.state == StateComponentSelected && *s.selectedComponent == t {
-		drawComponentOutline(*s.selectedComponent, rl.Yellow)
-		x, y := (*s.selectedComponent).GetPosition()
-		rl.DrawRectangleLines(x, y, gridComponentImageSize, gridComponentImageSize, rl.Yellow)
-	}
-}
-
 func (t *Transistor) GetID() ComponentID {
 	return t.ComponentID
 }
@@ -477,14 +452,16 @@ type CustomComponent struct {
 	ComponentType string
 	Subcomponents []Component
 	Inputs        []*Node
+	Outputs       []*Node
 	maxDefers     int
 }
 
-func NewCustomComponent(componentType string, subcomponents []Component, inputs []*Node) *CustomComponent {
+func NewCustomComponent(componentType string, subcomponents []Component, inputs, outputs []*Node) *CustomComponent {
 	return &CustomComponent{
 		ComponentType: componentType,
 		Subcomponents: subcomponents,
 		Inputs:        inputs,
+		Outputs:       outputs,
 		maxDefers:     4,
 	}
 }
@@ -505,12 +482,7 @@ func (c *CustomComponent) Ready() bool {
 }
 
 func (c *CustomComponent) Nodes() []*Node {
-	// TODO
-	return []*Node{}
-}
-
-func (c *CustomComponent) Render(s DrawingState) {
-	// TODO
+	return append(append([]*Node{}, c.Inputs...), c.Outputs...)
 }
 
 func (c *CustomComponent) GetID() ComponentID {
@@ -555,63 +527,28 @@ func SplitComponents(components []Component) (transistors []Component, resistors
 	return
 }
 
-func tick(components []Component) (deferred []Component, err error) {
-	debug := true
-	for _, component := range components {
-		if component.Ready() {
-			if debug {
-				fmt.Println("component ready\n", "before: ", component.Debug())
-			}
-			if err = component.Act(); err != nil {
-				return
-			}
-			if debug {
-				fmt.Println("after: ", component.Debug())
-			}
-		} else {
-			if debug {
-				fmt.Println(component.Debug(), "component not ready")
-			}
-			deferred = append(deferred, component)
-		}
-	}
-	return
+// Acts every component once its inputs are ready, deferring the ones that
+// are not for up to maxDefers rounds
+func ActComponents(components []Component, maxDefers int) error {
+	return actComponents(components, maxDefers, nil)
 }
 
-func ActComponents(components []Component, maxDefers int) (err error) {
-	deferredComponents := components
-	for range maxDefers {
-		transistors, resistors, others := SplitComponents(deferredComponents)
-		transistorsLen := len(transistors)
-		for {
-			transistors, err = tick(transistors)
-			if err != nil {
-				return
-			}
-			if len(transistors) == transistorsLen {
-				break
-			}
-			transistorsLen = len(transistors)
-		}
-		others, err = tick(others)
-		if err != nil {
-			return
-		}
-		resistors, err = tick(resistors)
-		if err != nil {
-			return
-		}
-		deferredComponents = append(transistors, others...)
-		deferredComponents = append(deferredComponents, resistors...)
-		if len(deferredComponents) == 0 {
-			break
+func actComponents(components []Component, maxDefers int, r *run) error {
+	scheduler := newScheduler(components, maxDefers, r)
+	for !scheduler.Done() {
+		if _, _, err := scheduler.Next(); err != nil {
+			return err
 		}
 	}
-	return
+	return nil
 }
 
 func (c *CustomComponent) Act() error {
-	return ActComponents(c.Subcomponents, c.maxDefers)
+	return c.actIn(nil)
+}
+
+func (c *CustomComponent) actIn(r *run) error {
+	return actComponents(c.Subcomponents, c.maxDefers, r)
 }
 
 func (c *CustomComponent) Debug() string {
@@ -0,0 +1,61 @@
+package sim
+
+import "testing"
+
+func TestCloneLeavesNodesUnconnected(t *testing.T) {
+	input := NewDrawableInput("Input", &Node{OffsetX: 0.7, OffsetY: 0.5}, On)
+	resistor := NewDrawableResistor("Resistor", &Node{OffsetY: 0.5}, &Node{OffsetX: 1, OffsetY: 0.5})
+	input.Node.Connect(resistor.Node1)
+
+	clone := input.Clone(ComponentID{Name: "Input 2", ID: "2"}).(*Terminal)
+	if len(clone.Node.Connections()) != 0 || clone.Node.Parent != clone {
+		t.Errorf("expected an unconnected node owned by the clone but got %s", clone.Node.Debug())
+	}
+	if clone.Node.OffsetX != 0.7 || clone.State() != On || clone.Name != "Input 2" {
+		t.Errorf("clone did not keep the offsets and state of the original: %+v", clone)
+	}
+	if len(input.Node.Connections()) != 1 || len(resistor.Node1.Connections()) != 1 {
+		t.Errorf("cloning changed the connections of the original")
+	}
+}
+
+func TestCloneGroup(t *testing.T) {
+	input := NewDrawableInput("Input", &Node{}, On)
+	resistor := NewDrawableResistor("Resistor", &Node{}, &Node{})
+	meter := NewDrawableMultimeter("Multimeter", &Node{})
+	input.Node.Connect(resistor.Node1)
+	resistor.Node2.Connect(meter.Node)
+
+	n := 0
+	clones := CloneGroup([]Component{input, resistor}, func(c Component) ComponentID {
+		n++
+		return ComponentID{Name: c.GetID().Name + " copy", ID: string(rune('0' + n))}
+	})
+	inputClone, resistorClone := clones[0].(*Terminal), clones[1].(*Resistor)
+	if inputClone.ID != "1" || resistorClone.ID != "2" || resistorClone.Name != "Resistor copy" {
+		t.Errorf("unexpected clone ids %+v and %+v", inputClone.ComponentID, resistorClone.ComponentID)
+	}
+	connections := inputClone.Node.Connections()
+	if len(connections) != 1 || connections[0] != resistorClone.Node1 {
+		t.Errorf("expected the cloned input to be connected to the cloned resistor only")
+	}
+	if len(resistorClone.Node2.Connections()) != 0 {
+		t.Errorf("expected the connection to the meter outside the group to be left out")
+	}
+	if len(meter.Node.Connections()) != 1 {
+		t.Errorf("cloning changed the connections of the meter")
+	}
+}
+
+func TestClockToggles(t *testing.T) {
+	node := NewNode("clock")
+	c := NewCircuit([]Component{NewClock("clock", node)}, 4, false)
+	for i, expected := range []NodeState{Off, On, Off, On} {
+		if err := c.Tick(); err != nil {
+			t.Fatal(err)
+		}
+		if node.State != expected {
+			t.Errorf("step %d: expected the clock to drive %s but got %s", i, expected, node.State)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package sim
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Stops Debugger.Continue once Component acts or Node changes to State,
+// whichever is set
+type Breakpoint struct {
+	Component Component
+	Node      *Node
+	State     NodeState
+}
+
+func (b Breakpoint) String() string {
+	if b.Node != nil {
+		return fmt.Sprintf("node %s becomes %s", NodeName(b.Node), b.State)
+	}
+	return ComponentName(b.Component) + " acts"
+}
+
+func (b Breakpoint) Hit(step *DebugStep) bool {
+	if b.Component != nil && step.Component == b.Component {
+		return true
+	}
+	return b.Node != nil && b.Node.State == b.State && slices.Contains(step.Changed, b.Node)
+}
+
+// Name of the node after its component and its ID, or its position among
+// the nodes of the component when it has none
+func NodeName(n *Node) string {
+	if n.Parent == nil {
+		return n.ID
+	}
+	id := n.ID
+	if id == "" {
+		id = fmt.Sprint(slices.Index(n.Parent.Nodes(), n))
+	}
+	return ComponentName(n.Parent) + "." + id
+}
+
+// Component acted by a debugger step, along with what it did
+type DebugStep struct {
+	Component Component
+	// Nodes the component changed the state of
+	Changed []*Node
+	// Components deferred while looking for one ready to act
+	Deferred []Component
+	// Whether the step started over, a feedback loop having been seeded with
+	// a state other than the one it settled on
+	Restarted bool
+}
+
+// Steps through a step of the circuit one Act at a time, driving the
+// terminals first so that the nets they drive can hit breakpoints too
+type Debugger struct {
+	circuit *Circuit
+	// terminals left to drive before the scheduler takes over
+	terminals []Component
+	run       *run
+	scheduler *Scheduler
+	// every node of the circuit, to find the ones each Act changed
+	nodes       []*Node
+	Breakpoints []Breakpoint
+	// times the step was started over
+	restarts int
+	done     bool
+}
+
+// Starts a step of the circuit to go through with the debugger
+func (c *Circuit) Debug(breakpoints ...Breakpoint) *Debugger {
+	d := &Debugger{circuit: c, nodes: c.nodes(), Breakpoints: breakpoints}
+	d.start()
+	return d
+}
+
+func (d *Debugger) start() {
+	c := d.circuit
+	d.run = c.begin()
+	d.terminals = slices.Clone(c.terminals)
+	d.scheduler = newScheduler(c.components, c.maxDefers, d.run)
+}
+
+// Whether the step is over, every component acted and the meters were read
+func (d *Debugger) Done() bool {
+	return d.done
+}
+
+// Drives the next terminal, or acts the next component ready to act once
+// every terminal is driven. Once none is left, the step is finished and the
+// returned step has no component
+func (d *Debugger) Step() (*DebugStep, error) {
+	if d.done {
+		return nil, nil
+	}
+	step := &DebugStep{}
+	err := d.run.traceConflict(d.step(step))
+	if err != nil {
+		d.done = true
+	}
+	return step, err
+}
+
+func (d *Debugger) step(step *DebugStep) error {
+	before := make([]NodeState, len(d.nodes))
+	for i, node := range d.nodes {
+		before[i] = node.State
+	}
+	if len(d.terminals) > 0 {
+		step.Component = d.terminals[0]
+		d.terminals = d.terminals[1:]
+		if err := drive(step.Component, d.run); err != nil {
+			return err
+		}
+	}
+	for step.Component == nil && !d.scheduler.Done() {
+		component, acted, err := d.scheduler.Next()
+		if err != nil {
+			step.Component = component
+			return err
+		}
+		if acted {
+			step.Component = component
+			break
+		}
+		step.Deferred = append(step.Deferred, component)
+	}
+	for i, node := range d.nodes {
+		if node.State != before[i] {
+			step.Changed = append(step.Changed, node)
+		}
+	}
+	if step.Component == nil && d.run.unsettled {
+		// like Tick, start over from the states the feedback loops settled on
+		d.restarts++
+		if d.restarts == d.circuit.maxDefers {
+			d.done = true
+			return d.circuit.unsettledError()
+		}
+		step.Restarted = true
+		d.start()
+		return nil
+	}
+	if step.Component == nil {
+		d.done = true
+		return d.circuit.finish(d.run)
+	}
+	return nil
+}
+
+// Steps until a breakpoint is hit, returning the step that hit it, or until
+// the step is over, returning nil
+func (d *Debugger) Continue() (*DebugStep, error) {
+	for !d.done {
+		step, err := d.Step()
+		if err != nil {
+			return step, err
+		}
+		for _, breakpoint := range d.Breakpoints {
+			if step.Component != nil && breakpoint.Hit(step) {
+				return step, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Terminals left to drive, or once they are driven the components left to
+// consider in the current pass of the scheduler, in order
+func (d *Debugger) Pending() []Component {
+	if len(d.terminals) > 0 {
+		return d.terminals
+	}
+	return d.scheduler.Pending()
+}
+
+// Components deferred to a later pass or round of the scheduler so far
+func (d *Debugger) Deferred() []Component {
+	return d.scheduler.Deferred()
+}
+
+// Rounds of the scheduler started so far
+func (d *Debugger) Round() int {
+	return d.scheduler.Round()
+}
+
+func (d *Debugger) Circuit() *Circuit {
+	return d.circuit
+}
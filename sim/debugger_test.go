@@ -0,0 +1,187 @@
+package sim
+
+import (
+	"slices"
+	"testing"
+)
+
+// Two NOT gates in a row, the second listed first so that it is deferred
+type debuggedNotGates struct {
+	c             *Circuit
+	input         Component
+	first, second Component
+	in, between   *Node
+	out           *Node
+}
+
+func newDebuggedNotGates() debuggedNotGates {
+	in := NewNode("in")
+	between, notGate1 := NewNotGate(in)
+	out, notGate2 := NewNotGate(between)
+	input := NewInput("in", in, On)
+	c := NewCircuit([]Component{input, notGate2, notGate1, NewMultimeter("out", out)}, 4, false)
+	return debuggedNotGates{c, input, notGate1, notGate2, in, between, out}
+}
+
+// Steps through the terminals, which come first, returning the last step
+func stepTerminals(t *testing.T, d *Debugger) *DebugStep {
+	var step *DebugStep
+	for range d.circuit.terminals {
+		var err error
+		if step, err = d.Step(); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := step.Component.(*Terminal); !ok {
+			t.Fatalf("expected a terminal to be driven but got %+v", step)
+		}
+	}
+	return step
+}
+
+func TestDebuggerSteps(t *testing.T) {
+	f := newDebuggedNotGates()
+	d := f.c.Debug()
+
+	if !slices.Equal(d.Pending(), f.c.terminals) {
+		t.Errorf("expected the terminals pending first but got %v", d.Pending())
+	}
+	step := stepTerminals(t, d)
+	if step.Component != f.input || !slices.Contains(step.Changed, f.in) || slices.Contains(step.Changed, f.between) || f.in.State != On {
+		t.Errorf("expected the input to drive its node on but got %+v", step)
+	}
+
+	step, err := d.Step()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second, between, out := f.first, f.second, f.between, f.out
+	if step.Component != first || !slices.Equal(step.Deferred, []Component{second}) {
+		t.Errorf("expected the first gate to act after deferring the second but got %+v", step)
+	}
+	if !slices.Contains(step.Changed, between) || slices.Contains(step.Changed, out) || between.State != Off {
+		t.Errorf("expected the first gate to change only its output but changed %v", step.Changed)
+	}
+	if !slices.Equal(d.Pending(), []Component{second}) || d.Round() != 1 {
+		t.Errorf("expected the second gate pending on round 1 but got %v on round %d", d.Pending(), d.Round())
+	}
+
+	step, err = d.Step()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if step.Component != second || !slices.Contains(step.Changed, out) || out.State != On {
+		t.Errorf("expected the second gate to drive the output on but got %+v", step)
+	}
+	if d.Done() {
+		t.Errorf("expected the meters to be read on another step")
+	}
+
+	step, err = d.Step()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if step.Component != nil || !d.Done() || f.c.Steps() != 1 {
+		t.Errorf("expected the circuit step to be over but got %+v after %d steps", step, f.c.Steps())
+	}
+	if step, _ := d.Step(); step != nil {
+		t.Errorf("expected no steps once done but got %+v", step)
+	}
+}
+
+func TestDebuggerBreakpoints(t *testing.T) {
+	tt := []struct {
+		name       string
+		breakpoint func(f debuggedNotGates) Breakpoint
+		// component the debugger stops at, nil when running to the end
+		expected func(f debuggedNotGates) Component
+	}{
+		{
+			"component acts",
+			func(f debuggedNotGates) Breakpoint { return Breakpoint{Component: f.first} },
+			func(f debuggedNotGates) Component { return f.first },
+		},
+		{
+			"node becomes on",
+			func(f debuggedNotGates) Breakpoint { return Breakpoint{Node: f.out, State: On} },
+			func(f debuggedNotGates) Component { return f.second },
+		},
+		{
+			"node driven by a terminal",
+			func(f debuggedNotGates) Breakpoint { return Breakpoint{Node: f.in, State: On} },
+			func(f debuggedNotGates) Component { return f.input },
+		},
+		{
+			"node never becomes off",
+			func(f debuggedNotGates) Breakpoint { return Breakpoint{Node: f.out, State: Off} },
+			func(f debuggedNotGates) Component { return nil },
+		},
+	}
+	for _, tc := range tt {
+		f := newDebuggedNotGates()
+		d := f.c.Debug(tc.breakpoint(f))
+		step, err := d.Continue()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := tc.expected(f)
+		if expected == nil {
+			if step != nil || !d.Done() {
+				t.Errorf("%s: expected to run to the end but stopped at %+v", tc.name, step)
+			}
+			continue
+		}
+		if step == nil || step.Component != expected {
+			t.Errorf("%s: stopped at %+v", tc.name, step)
+		}
+	}
+}
+
+func TestDebuggerMatchesTick(t *testing.T) {
+	f := newDebuggedNotGates()
+	c, out := f.c, f.out
+	d := c.Debug()
+	for !d.Done() {
+		if _, err := d.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	debugged := out.State
+	if err := c.Tick(); err != nil {
+		t.Fatal(err)
+	}
+	if out.State != debugged || c.Steps() != 2 {
+		t.Errorf("expected a tick to match the debugged step, got %s and %s", out.State, debugged)
+	}
+}
+
+func TestDebuggerIgnoresOtherCircuits(t *testing.T) {
+	f := newDebuggedNotGates()
+	stray, _ := NewNotGate(NewNode("stray"))
+	d := f.c.Debug()
+	if slices.Contains(d.nodes, stray) {
+		t.Errorf("expected the nodes of a gate outside the circuit not to be watched")
+	}
+	if !slices.Contains(d.nodes, f.between) || !slices.Contains(d.nodes, SharedSourceNode) {
+		t.Errorf("expected the nodes of the circuit and its supply to be watched")
+	}
+}
+
+func TestDebuggerRestartsUnsettledSteps(t *testing.T) {
+	data := NewNode("data")
+	enable := NewNode("enable")
+	output, latch := NewDLatch(data, enable)
+	c := NewCircuit([]Component{NewInput("data", data, On), NewInput("enable", enable, On), latch}, 4, false)
+	d := c.Debug()
+	restarted := false
+	for !d.Done() {
+		step, err := d.Step()
+		if err != nil {
+			t.Fatal(err)
+		}
+		restarted = restarted || step.Restarted
+	}
+	// the latch is seeded with off on power on, then settles on
+	if !restarted || output.State != On || c.Steps() != 1 {
+		t.Errorf("expected the step to start over and store on but got %s after restarting: %v", output.State, restarted)
+	}
+}
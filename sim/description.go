@@ -0,0 +1,439 @@
+package sim
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Circuit description language, one statement per line:
+//
+//	# half adder built from library blocks
+//	circuit HalfAdder
+//	input a, b
+//	output sum, carry
+//	x0 = XorGate(a, b)
+//	a0 = AndGate(a, b) @behavioral
+//	sum = x0
+//	carry = a0[0]
+//
+// Instances take library blocks or the Transistor(source, gate, drain) and
+// Resistor(node1, node2) primitives. Outputs of an instance are referenced
+// by index, or by the instance name when it has a single output. The vdd and
+// gnd nets are always declared
+
+type SourcePosition struct {
+	Line   int
+	Column int
+}
+
+type DescriptionError struct {
+	SourcePosition
+	Message string
+}
+
+func (e *DescriptionError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+func descriptionErrorf(position SourcePosition, format string, args ...any) error {
+	return &DescriptionError{position, fmt.Sprintf(format, args...)}
+}
+
+// Reference to a net, Index is -1 unless an instance output is selected
+type DescriptionRef struct {
+	SourcePosition
+	Name  string
+	Index int
+}
+
+func (r DescriptionRef) String() string {
+	if r.Index < 0 {
+		return r.Name
+	}
+	return fmt.Sprintf("%s[%d]", r.Name, r.Index)
+}
+
+// Instance when Type is set, otherwise a connection from Target to Source
+type DescriptionStatement struct {
+	SourcePosition
+	Target    string
+	Type      string
+	Arguments []DescriptionRef
+	Level     *DescriptionRef
+	Source    DescriptionRef
+}
+
+type CircuitDescription struct {
+	Name       string
+	Inputs     []DescriptionRef
+	Outputs    []DescriptionRef
+	Wires      []DescriptionRef
+	Statements []DescriptionStatement
+}
+
+type descriptionToken struct {
+	SourcePosition
+	// identifier, number, punctuation or end of line
+	kind  byte
+	value string
+}
+
+const (
+	tokenIdentifier  = 'a'
+	tokenNumber      = '0'
+	tokenPunctuation = '.'
+	tokenEnd         = '$'
+)
+
+func descriptionTokens(line string, number int) ([]descriptionToken, error) {
+	var tokens []descriptionToken
+	runes := []rune(line)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		position := SourcePosition{number, i + 1}
+		switch {
+		case r == '#' || r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			i = len(runes)
+		case unicode.IsSpace(r):
+			i++
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, descriptionToken{position, tokenIdentifier, string(runes[start:i])})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, descriptionToken{position, tokenNumber, string(runes[start:i])})
+		case strings.ContainsRune("=(),[]@", r):
+			tokens = append(tokens, descriptionToken{position, tokenPunctuation, string(r)})
+			i++
+		default:
+			return nil, descriptionErrorf(position, "unexpected character %q", r)
+		}
+	}
+	return append(tokens, descriptionToken{SourcePosition{number, len(runes) + 1}, tokenEnd, ""}), nil
+}
+
+type descriptionParser struct {
+	tokens []descriptionToken
+	next   int
+}
+
+func (p *descriptionParser) peek() descriptionToken {
+	return p.tokens[p.next]
+}
+
+func (p *descriptionParser) take() descriptionToken {
+	token := p.tokens[p.next]
+	if token.kind != tokenEnd {
+		p.next++
+	}
+	return token
+}
+
+func describeToken(token descriptionToken) string {
+	if token.kind == tokenEnd {
+		return "end of line"
+	}
+	return fmt.Sprintf("%q", token.value)
+}
+
+func (p *descriptionParser) expect(kind byte, value string, what string) (descriptionToken, error) {
+	token := p.take()
+	if token.kind != kind || value != "" && token.value != value {
+		return token, descriptionErrorf(token.SourcePosition, "expected %s but found %s", what, describeToken(token))
+	}
+	return token, nil
+}
+
+func (p *descriptionParser) end() error {
+	_, err := p.expect(tokenEnd, "", "end of line")
+	return err
+}
+
+func (p *descriptionParser) ref() (DescriptionRef, error) {
+	name, err := p.expect(tokenIdentifier, "", "net name")
+	if err != nil {
+		return DescriptionRef{}, err
+	}
+	ref := DescriptionRef{name.SourcePosition, name.value, -1}
+	if p.peek().value != "[" {
+		return ref, nil
+	}
+	p.take()
+	index, err := p.expect(tokenNumber, "", "output index")
+	if err != nil {
+		return ref, err
+	}
+	ref.Index, _ = strconv.Atoi(index.value)
+	_, err = p.expect(tokenPunctuation, "]", `"]"`)
+	return ref, err
+}
+
+func (p *descriptionParser) names() ([]DescriptionRef, error) {
+	var names []DescriptionRef
+	for {
+		name, err := p.expect(tokenIdentifier, "", "net name")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, DescriptionRef{name.SourcePosition, name.value, -1})
+		if p.peek().value != "," {
+			return names, p.end()
+		}
+		p.take()
+	}
+}
+
+func (p *descriptionParser) statement(d *CircuitDescription) error {
+	first := p.take()
+	if first.kind == tokenEnd {
+		return nil
+	}
+	if first.kind != tokenIdentifier {
+		return descriptionErrorf(first.SourcePosition, "expected a statement but found %s", describeToken(first))
+	}
+	var err error
+	switch first.value {
+	case "circuit":
+		var name descriptionToken
+		if name, err = p.expect(tokenIdentifier, "", "circuit name"); err != nil {
+			return err
+		}
+		d.Name = name.value
+		return p.end()
+	case "input":
+		names, err := p.names()
+		d.Inputs = append(d.Inputs, names...)
+		return err
+	case "output":
+		names, err := p.names()
+		d.Outputs = append(d.Outputs, names...)
+		return err
+	case "wire":
+		names, err := p.names()
+		d.Wires = append(d.Wires, names...)
+		return err
+	}
+
+	statement := DescriptionStatement{SourcePosition: first.SourcePosition, Target: first.value}
+	if _, err = p.expect(tokenPunctuation, "=", `"="`); err != nil {
+		return err
+	}
+	if p.next+1 >= len(p.tokens) || p.tokens[p.next+1].value != "(" {
+		if statement.Source, err = p.ref(); err != nil {
+			return err
+		}
+		d.Statements = append(d.Statements, statement)
+		return p.end()
+	}
+
+	statement.Type = p.take().value
+	p.take()
+	for p.peek().value != ")" {
+		if len(statement.Arguments) > 0 {
+			if _, err = p.expect(tokenPunctuation, ",", `"," or ")"`); err != nil {
+				return err
+			}
+		}
+		argument, err := p.ref()
+		if err != nil {
+			return err
+		}
+		statement.Arguments = append(statement.Arguments, argument)
+	}
+	p.take()
+	if p.peek().value == "@" {
+		p.take()
+		level, err := p.expect(tokenIdentifier, "", "abstraction level")
+		if err != nil {
+			return err
+		}
+		statement.Level = &DescriptionRef{level.SourcePosition, level.value, -1}
+	}
+	d.Statements = append(d.Statements, statement)
+	return p.end()
+}
+
+func ParseCircuitDescription(r io.Reader) (*CircuitDescription, error) {
+	d := &CircuitDescription{}
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		tokens, err := descriptionTokens(scanner.Text(), number)
+		if err != nil {
+			return nil, err
+		}
+		p := &descriptionParser{tokens: tokens}
+		if err := p.statement(d); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Number of outputs of every instance type, primitives have none
+func descriptionOutputs(statement DescriptionStatement) (int, error) {
+	switch statement.Type {
+	case "Transistor", "Resistor":
+		if statement.Level != nil {
+			return 0, descriptionErrorf(statement.Level.SourcePosition, "%s has no abstraction levels", statement.Type)
+		}
+		return 0, nil
+	}
+	block, ok := FindBlock(statement.Type)
+	if !ok {
+		return 0, descriptionErrorf(statement.SourcePosition, "unknown component type %s", statement.Type)
+	}
+	return block.OutputCount, nil
+}
+
+// Builds the described circuit. Every name is declared before the
+// statements are built, so instances may be used before their definition
+func (d *CircuitDescription) Build() (*Netlist, error) {
+	n := newNetlist()
+	n.Name = d.Name
+	n.Nets["vdd"] = n.constant(On)
+	n.Nets["gnd"] = n.constant(Off)
+
+	declared := map[string]SourcePosition{"vdd": {}, "gnd": {}}
+	outputs := map[string]int{}
+	declare := func(position SourcePosition, name string) error {
+		if _, ok := declared[name]; ok {
+			return descriptionErrorf(position, "%s is already declared", name)
+		}
+		declared[name] = position
+		return nil
+	}
+	for _, names := range [][]DescriptionRef{d.Inputs, d.Outputs, d.Wires} {
+		for _, name := range names {
+			if err := declare(name.SourcePosition, name.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, input := range d.Inputs {
+		n.Inputs = append(n.Inputs, input.Name)
+	}
+	for _, output := range d.Outputs {
+		n.Outputs = append(n.Outputs, output.Name)
+	}
+	for _, statement := range d.Statements {
+		if statement.Type == "" {
+			continue
+		}
+		count, err := descriptionOutputs(statement)
+		if err != nil {
+			return nil, err
+		}
+		if err := declare(statement.SourcePosition, statement.Target); err != nil {
+			return nil, err
+		}
+		outputs[statement.Target] = count
+	}
+
+	net := func(ref DescriptionRef) (*Node, error) {
+		if _, ok := declared[ref.Name]; !ok {
+			return nil, descriptionErrorf(ref.SourcePosition, "undeclared net %s", ref.Name)
+		}
+		count, instance := outputs[ref.Name]
+		switch {
+		case instance && ref.Index < 0 && count != 1:
+			return nil, descriptionErrorf(ref.SourcePosition, "%s has %d outputs, select one with %s[index]", ref.Name, count, ref.Name)
+		case instance && ref.Index < 0:
+			return n.net(ref.Name + "[0]"), nil
+		case instance && ref.Index >= count:
+			return nil, descriptionErrorf(ref.SourcePosition, "%s has no output %d", ref.Name, ref.Index)
+		case !instance && ref.Index >= 0:
+			return nil, descriptionErrorf(ref.SourcePosition, "%s is not an instance", ref.Name)
+		}
+		return n.net(ref.String()), nil
+	}
+
+	for _, statement := range d.Statements {
+		var arguments []*Node
+		for _, argument := range statement.Arguments {
+			node, err := net(argument)
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, node)
+		}
+
+		switch statement.Type {
+		case "":
+			if _, ok := outputs[statement.Target]; ok {
+				return nil, descriptionErrorf(statement.SourcePosition, "cannot connect to instance %s, connect to one of its outputs instead", statement.Target)
+			}
+			target, err := net(DescriptionRef{statement.SourcePosition, statement.Target, -1})
+			if err != nil {
+				return nil, err
+			}
+			source, err := net(statement.Source)
+			if err != nil {
+				return nil, err
+			}
+			target.Connect(source)
+		case "Transistor":
+			if len(arguments) != 3 {
+				return nil, descriptionErrorf(statement.SourcePosition, "Transistor takes source, gate and drain but got %d nets", len(arguments))
+			}
+			n.Components = append(n.Components, NewTransistor(statement.Target, arguments[0], arguments[1], arguments[2]))
+		case "Resistor":
+			if len(arguments) != 2 {
+				return nil, descriptionErrorf(statement.SourcePosition, "Resistor takes two nets but got %d", len(arguments))
+			}
+			n.Components = append(n.Components, NewResistor(statement.Target, arguments[0], arguments[1]))
+		default:
+			block, _ := FindBlock(statement.Type)
+			if len(arguments) != block.InputCount {
+				return nil, descriptionErrorf(statement.SourcePosition, "%s takes %d inputs but got %d", block.Name, block.InputCount, len(arguments))
+			}
+			level := LevelTransistor
+			if statement.Level != nil {
+				var err error
+				if level, err = parseAbstractionLevel(statement.Level.Name); err != nil {
+					return nil, descriptionErrorf(statement.Level.SourcePosition, "%s", err.Error())
+				}
+			}
+			instance := NewBlockInstance(statement.Target, block, arguments, level)
+			for i, output := range instance.Outputs {
+				n.net(fmt.Sprintf("%s[%d]", statement.Target, i)).Connect(output)
+			}
+			n.Components = append(n.Components, instance)
+		}
+	}
+	return n.finish(), nil
+}
+
+func LoadCircuitDescription(r io.Reader) (*Netlist, error) {
+	d, err := ParseCircuitDescription(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.Build()
+}
+
+func OpenCircuitDescription(path string) (*Netlist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	netlist, err := LoadCircuitDescription(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return netlist, nil
+}
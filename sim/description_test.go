@@ -0,0 +1,95 @@
+package sim
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadCircuitDescription(t *testing.T) {
+	description := `
+# two bit ripple adder, the carry is used before its adder is declared
+circuit RippleAdder
+input a0, a1, b0, b1
+output s0, s1, cout
+wire carry
+
+s0 = fa0[0]
+s1 = fa1[0]
+cout = fa1[1]
+fa1 = FullAdder(a1, b1, carry) @behavioral
+fa0 = FullAdder(a0, b0, gnd)
+carry = fa0[1]
+`
+	netlist, err := LoadCircuitDescription(strings.NewReader(description))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if netlist.Name != "RippleAdder" {
+		t.Errorf("expected circuit name RippleAdder but got %s", netlist.Name)
+	}
+	checkNetlist(t, netlist, func(inputs []bool) []bool {
+		a, b := 0, 0
+		for i := range 2 {
+			if inputs[i] {
+				a |= 1 << i
+			}
+			if inputs[2+i] {
+				b |= 1 << i
+			}
+		}
+		sum := a + b
+		return []bool{sum&1 != 0, sum&2 != 0, sum&4 != 0}
+	})
+}
+
+func TestLoadCircuitDescriptionPrimitives(t *testing.T) {
+	description := `
+input a
+output out
+t0 = Transistor(vdd, a, out)
+r0 = Resistor(out, gnd) // pulls the output down when the transistor is off
+`
+	netlist, err := LoadCircuitDescription(strings.NewReader(description))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNetlist(t, netlist, func(inputs []bool) []bool {
+		return []bool{inputs[0]}
+	})
+	if ConnectedNodes(netlist.Nets["vdd"])[SharedSourceNode] || ConnectedNodes(netlist.Nets["gnd"])[SharedGroundNode] {
+		t.Errorf("expected vdd and gnd to be driven by the netlist instead of the shared supply")
+	}
+}
+
+func TestCircuitDescriptionErrors(t *testing.T) {
+	tt := []struct {
+		description    string
+		expectedLine   int
+		expectedColumn int
+		expectedError  string
+	}{
+		{"input a\noutput b\nb = c", 3, 5, "undeclared net c"},
+		{"input a, \n", 1, 10, "expected net name but found end of line"},
+		{"input a\nx = NotGate(a b)", 2, 15, `expected "," or ")" but found "b"`},
+		{"input a\nx = Adder(a)", 2, 1, "unknown component type Adder"},
+		{"input a\nx = NotGate(a, a)", 2, 1, "NotGate takes 1 inputs but got 2"},
+		{"input a\noutput b\nx = FullAdder(a, a, a)\nb = x", 4, 5, "x has 2 outputs, select one with x[index]"},
+		{"input a\noutput b\nx = NotGate(a)\nb = x[1]", 4, 5, "x has no output 1"},
+		{"input a\nx = NotGate(a) @gate", 2, 17, `unknown abstraction level "gate"`},
+		{"input a\ninput a", 2, 7, "a is already declared"},
+		{"input a;", 1, 8, "unexpected character ';'"},
+	}
+	for _, tc := range tt {
+		_, err := LoadCircuitDescription(strings.NewReader(tc.description))
+		var descriptionError *DescriptionError
+		if !errors.As(err, &descriptionError) {
+			t.Errorf("expected a description error for %q but got %v", tc.description, err)
+			continue
+		}
+		if descriptionError.Line != tc.expectedLine || descriptionError.Column != tc.expectedColumn || descriptionError.Message != tc.expectedError {
+			t.Errorf("expected error %d:%d %q for %q but got %d:%d %q", tc.expectedLine, tc.expectedColumn, tc.expectedError,
+				tc.description, descriptionError.Line, descriptionError.Column, descriptionError.Message)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Loads a design by the extension of path: schematics and Yosys netlists
+// are .json files, .blif and .v are gate-level netlists and anything else is
+// read as a circuit description
+func LoadDesign(path string) (*Netlist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var netlist *Netlist
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var fields map[string]json.RawMessage
+		if err := json.NewDecoder(f).Decode(&fields); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, ok := fields["modules"]; ok {
+			netlist, err = ImportYosysJSON(f)
+			break
+		}
+		var schematic *Schematic
+		if schematic, err = ReadSchematic(f); err != nil {
+			break
+		}
+		var components []Component
+		if components, err = schematic.Build(); err == nil {
+			netlist = NewNetlist(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), components)
+		}
+	case ".blif":
+		netlist, err = ImportBLIF(f)
+	case ".v":
+		netlist, err = ImportStructuralVerilog(f)
+	default:
+		netlist, err = LoadCircuitDescription(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return netlist, nil
+}
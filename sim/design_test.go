@@ -0,0 +1,43 @@
+package sim
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testHalfAdderDescription = `
+circuit HalfAdder
+input a, b
+output sum, carry
+x0 = XorGate(a, b)
+a0 = AndGate(a, b) @behavioral
+sum = x0
+carry = a0
+`
+
+func writeTestFile(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDesignSchematic(t *testing.T) {
+	var builder strings.Builder
+	if err := WriteSchematic(&builder, newTestSchematic()); err != nil {
+		t.Fatal(err)
+	}
+	netlist, err := LoadDesign(writeTestFile(t, "not.json", builder.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := netlist.Circuit.Tick(); err != nil {
+		t.Fatal(err)
+	}
+	if state, err := netlist.Output("Multimeter 1"); err != nil || state != Off {
+		t.Errorf("expected Multimeter 1 to measure off but got %s %v", state, err)
+	}
+}
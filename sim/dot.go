@@ -0,0 +1,269 @@
+package sim
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strings"
+)
+
+type DotOptions struct {
+	// Custom components drawn as a single box instead of a cluster
+	Collapsed map[Component]bool
+	// Component types that are always collapsed, e.g. "XorGate"
+	CollapsedTypes map[string]bool
+}
+
+func (o DotOptions) collapsed(c Component) bool {
+	if o.Collapsed[c] {
+		return true
+	}
+	switch c := c.(type) {
+	case *CustomComponent:
+		return o.CollapsedTypes[c.ComponentType]
+	case *BlockInstance:
+		return o.CollapsedTypes[c.Block.Name]
+	}
+	return false
+}
+
+// Same palette as the drill-down view
+func dotStateColor(n *Node) string {
+	switch n.State {
+	case On:
+		return "yellow"
+	case Off:
+		return "white"
+	default:
+		return "gray"
+	}
+}
+
+// Graphviz exporter for the node graph. Every box lists the nodes of its
+// component as ports, edges follow the connections between nodes
+type dotWriter struct {
+	options DotOptions
+	lines   []string
+	ids     int
+
+	// port cell or point node where each node is drawn
+	endpoints map[*Node]string
+	// nodes hidden inside a collapsed component, mapped to its box
+	hidden map[*Node]string
+	// port cells of collapsed components listing each node
+	cells map[*Node][]string
+	// ports of each collapsed component box
+	boxPorts map[string]map[*Node]bool
+	nodes    []*Node
+	tracked  map[*Node]bool
+}
+
+func (d *dotWriter) id(prefix string) string {
+	d.ids++
+	return fmt.Sprintf("%s%d", prefix, d.ids)
+}
+
+func (d *dotWriter) line(depth int, format string, args ...any) {
+	d.lines = append(d.lines, strings.Repeat("  ", depth+1)+fmt.Sprintf(format, args...))
+}
+
+func (d *dotWriter) track(n *Node) {
+	if !d.tracked[n] {
+		d.tracked[n] = true
+		d.nodes = append(d.nodes, n)
+	}
+}
+
+func dotPortCell(port int, n *Node) string {
+	label := n.ID
+	if label == "" {
+		label = fmt.Sprintf("p%d", port)
+	}
+	return fmt.Sprintf(`<td port="p%d" bgcolor="%s">%s</td>`, port, dotStateColor(n), html.EscapeString(label))
+}
+
+// Draws c as a box with a row of input ports above its label and a row of
+// output ports below it, returning the box id
+func (d *dotWriter) box(depth int, c Component) (string, []*Node) {
+	id := d.id("c")
+	inputs, outputs := Ports(c)
+	ports := slices.Concat(inputs, outputs)
+	var label strings.Builder
+	label.WriteString(`<table border="0" cellborder="1" cellspacing="0">`)
+	if len(inputs) > 0 {
+		label.WriteString("<tr>")
+		for i, input := range inputs {
+			label.WriteString(dotPortCell(i, input))
+		}
+		label.WriteString("</tr>")
+	}
+	fmt.Fprintf(&label, `<tr><td colspan="%d"><b>%s</b></td></tr>`, max(len(inputs), len(outputs), 1), html.EscapeString(ComponentLabel(c)))
+	if len(outputs) > 0 {
+		label.WriteString("<tr>")
+		for i, output := range outputs {
+			label.WriteString(dotPortCell(len(inputs)+i, output))
+		}
+		label.WriteString("</tr>")
+	}
+	label.WriteString("</table>")
+	d.line(depth, "%s [shape=plaintext, label=<%s>];", id, label.String())
+	return id, ports
+}
+
+func (d *dotWriter) point(depth int, n *Node) {
+	id := d.id("n")
+	d.endpoints[n] = id
+	d.line(depth, "%s [shape=circle, style=filled, fillcolor=%s, fixedsize=true, width=0.2, label=\"\", xlabel=%q];", id, dotStateColor(n), n.ID)
+}
+
+// Hides every node nested in c inside the collapsed box
+func (d *dotWriter) hide(box string, c Component) {
+	inputs, outputs := Ports(c)
+	for _, n := range slices.Concat(c.Nodes(), inputs, outputs) {
+		d.track(n)
+		if _, ok := d.hidden[n]; !ok {
+			d.hidden[n] = box
+		}
+	}
+	for _, subcomponent := range Subcomponents(c) {
+		d.hide(box, subcomponent)
+	}
+}
+
+func (d *dotWriter) component(depth int, c Component) {
+	subcomponents := Subcomponents(c)
+	if subcomponents == nil {
+		id, ports := d.box(depth, c)
+		for i, n := range ports {
+			d.track(n)
+			d.endpoints[n] = fmt.Sprintf("%s:p%d", id, i)
+		}
+		return
+	}
+	if d.options.collapsed(c) {
+		id, ports := d.box(depth, c)
+		d.boxPorts[id] = map[*Node]bool{}
+		for i, n := range ports {
+			d.track(n)
+			d.cells[n] = append(d.cells[n], fmt.Sprintf("%s:p%d", id, i))
+			d.boxPorts[id][n] = true
+		}
+		d.hide(id, c)
+		return
+	}
+
+	d.line(depth, "subgraph %s {", d.id("cluster_"))
+	d.line(depth+1, "label=%q;", ComponentLabel(c))
+	inputs, outputs := Ports(c)
+	for _, n := range slices.Concat(inputs, outputs) {
+		d.track(n)
+		// nodes owned by the component itself have no other box to live in
+		if n.Parent == c {
+			d.point(depth+1, n)
+		}
+	}
+	for _, subcomponent := range subcomponents {
+		d.component(depth+1, subcomponent)
+	}
+	d.line(depth, "}")
+}
+
+func (d *dotWriter) write(components []Component) {
+	for _, c := range components {
+		d.component(0, c)
+	}
+
+	// free wires between the components. The shared nodes are connected to
+	// every component ever built, so owned nodes are never followed
+	for i := 0; i < len(d.nodes); i++ {
+		box, inside := d.hidden[d.nodes[i]]
+		inside = inside && !d.boxPorts[box][d.nodes[i]]
+		for _, n := range d.nodes[i].connections {
+			if n.Parent != nil || d.tracked[n] {
+				continue
+			}
+			d.track(n)
+			// wires inside a collapsed component stay hidden
+			if inside && !d.boxPorts[box][n] {
+				d.hidden[n] = box
+			}
+		}
+	}
+	for _, n := range d.nodes {
+		if _, ok := d.endpoints[n]; ok {
+			continue
+		}
+		cells := d.cells[n]
+		switch {
+		case len(cells) == 1 && d.onlyReaches(n, strings.Split(cells[0], ":")[0]):
+			d.endpoints[n] = cells[0]
+		case len(cells) == 0 && d.hidden[n] != "":
+		default:
+			d.point(0, n)
+			for _, cell := range cells {
+				d.line(0, "%s -- %s [style=dashed];", cell, d.endpoints[n])
+			}
+		}
+	}
+
+	edges := map[string]bool{}
+	for _, n := range d.nodes {
+		from, fromHidden := d.endpoint(n)
+		for _, m := range n.connections {
+			if !d.tracked[m] {
+				continue
+			}
+			to, toHidden := d.endpoint(m)
+			// a hidden node reaching one of the ports of its own box is
+			// already represented by the port cell
+			if fromHidden && d.boxPorts[from][m] || toHidden && d.boxPorts[to][n] {
+				continue
+			}
+			if from == to || edges[to+" -- "+from] || edges[from+" -- "+to] {
+				continue
+			}
+			edges[from+" -- "+to] = true
+			d.line(0, "%s -- %s;", from, to)
+		}
+	}
+}
+
+// Whether every connection of n goes to a node hidden inside box
+func (d *dotWriter) onlyReaches(n *Node, box string) bool {
+	for _, m := range n.connections {
+		if d.tracked[m] && (d.hidden[m] != box || d.boxPorts[box][m]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *dotWriter) endpoint(n *Node) (string, bool) {
+	if endpoint, ok := d.endpoints[n]; ok {
+		return endpoint, false
+	}
+	return d.hidden[n], true
+}
+
+// Writes the node graph of components as an undirected Graphviz graph
+func WriteDot(w io.Writer, components []Component, options DotOptions) error {
+	d := &dotWriter{
+		options:   options,
+		endpoints: map[*Node]string{},
+		hidden:    map[*Node]string{},
+		cells:     map[*Node][]string{},
+		boxPorts:  map[string]map[*Node]bool{},
+		tracked:   map[*Node]bool{},
+	}
+	d.write(components)
+	lines := []string{"graph circuit {", "  rankdir=LR;", "  node [fontname=\"Helvetica\", fontsize=10];"}
+	lines = append(lines, d.lines...)
+	lines = append(lines, "}")
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func (c *Circuit) WriteDot(w io.Writer, options DotOptions) error {
+	return WriteDot(w, c.Components(), options)
+}
@@ -0,0 +1,79 @@
+package sim
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestDotCircuit() (*Circuit, *CustomComponent) {
+	input1 := NewNode("A")
+	input2 := NewNode("B")
+	_, _, adder := NewSimpleAdder(input1, input2)
+	c := NewCircuit([]Component{NewInput("A", input1, On), NewInput("B", input2, Off), adder}, 20, false)
+	return c, adder
+}
+
+func TestWriteDotExpanded(t *testing.T) {
+	c, _ := newTestDotCircuit()
+	if err := c.Tick(); err != nil {
+		t.Fatal(err)
+	}
+	var builder strings.Builder
+	if err := c.WriteDot(&builder, DotOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	dot := builder.String()
+	for _, expected := range []string{
+		"graph circuit {",
+		`label="SimpleAdder";`,
+		`label="XorGate";`,
+		`label="AndGate";`,
+		`fillcolor=yellow, fixedsize=true, width=0.2, label="", xlabel="A"`,
+		`fillcolor=white, fixedsize=true, width=0.2, label="", xlabel="B"`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("expected graph to contain %q but got\n%s", expected, dot)
+		}
+	}
+	if strings.Count(dot, "{") != strings.Count(dot, "}") {
+		t.Errorf("unbalanced braces in graph\n%s", dot)
+	}
+}
+
+func TestWriteDotCollapsed(t *testing.T) {
+	tt := []struct {
+		options          DotOptions
+		expectedClusters int
+	}{
+		{options: DotOptions{CollapsedTypes: map[string]bool{"XorGate": true, "AndGate": true}}, expectedClusters: 1},
+		{options: DotOptions{CollapsedTypes: map[string]bool{"AndGate": true}}, expectedClusters: 4},
+	}
+	for _, tc := range tt {
+		c, _ := newTestDotCircuit()
+		var builder strings.Builder
+		if err := c.WriteDot(&builder, tc.options); err != nil {
+			t.Fatal(err)
+		}
+		dot := builder.String()
+		if clusters := strings.Count(dot, "subgraph cluster_"); clusters != tc.expectedClusters {
+			t.Errorf("expected %d clusters but got %d in\n%s", tc.expectedClusters, clusters, dot)
+		}
+		if !strings.Contains(dot, "<b>AndGate</b>") {
+			t.Errorf("expected a collapsed AndGate box in\n%s", dot)
+		}
+	}
+
+	_, adder := newTestDotCircuit()
+	var builder strings.Builder
+	if err := WriteDot(&builder, []Component{adder}, DotOptions{Collapsed: map[Component]bool{adder: true}}); err != nil {
+		t.Fatal(err)
+	}
+	dot := builder.String()
+	if strings.Contains(dot, "cluster_") || strings.Contains(dot, "Transistor") {
+		t.Errorf("expected the adder to be drawn as a single box but got\n%s", dot)
+	}
+	// ports of the collapsed adder are the only wires left
+	if !strings.Contains(dot, `port="p0" bgcolor="gray">A</td>`) {
+		t.Errorf("expected port A on the collapsed adder box in\n%s", dot)
+	}
+}
@@ -0,0 +1,194 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// Instantiates a block connected to the given input nodes, returning its
+// output nodes and the component that drives them
+type Builder func(inputs []*Node) (outputs []*Node, component Component)
+
+// Logic block available both at transistor level and as a behavioral model
+type Block struct {
+	Name        string
+	InputCount  int
+	OutputCount int
+	Transistor  Builder
+	Behavioral  Builder
+}
+
+func unaryBuilder[C Component](build func(input *Node) (*Node, C)) Builder {
+	return func(inputs []*Node) ([]*Node, Component) {
+		out, component := build(inputs[0])
+		return []*Node{out}, component
+	}
+}
+
+func binaryBuilder[C Component](build func(input1, input2 *Node) (*Node, C)) Builder {
+	return func(inputs []*Node) ([]*Node, Component) {
+		out, component := build(inputs[0], inputs[1])
+		return []*Node{out}, component
+	}
+}
+
+func simpleAdderBuilder[C Component](build func(input1, input2 *Node) (*Node, *Node, C)) Builder {
+	return func(inputs []*Node) ([]*Node, Component) {
+		out, carry, component := build(inputs[0], inputs[1])
+		return []*Node{out, carry}, component
+	}
+}
+
+func fullAdderBuilder[C Component](build func(input1, input2, carryIn *Node) (*Node, *Node, C)) Builder {
+	return func(inputs []*Node) ([]*Node, Component) {
+		out, carry, component := build(inputs[0], inputs[1], inputs[2])
+		return []*Node{out, carry}, component
+	}
+}
+
+func adderSubtractorBuilder[C Component](build func(input1, input2, carryIn, operation *Node) (*Node, *Node, C)) Builder {
+	return func(inputs []*Node) ([]*Node, Component) {
+		out, carry, component := build(inputs[0], inputs[1], inputs[2], inputs[3])
+		return []*Node{out, carry}, component
+	}
+}
+
+// Every block from gate.go and adder.go paired with its behavioral model
+var Blocks = []Block{
+	{"NotGate", 1, 1, unaryBuilder(NewNotGate), unaryBuilder(NewBehavioralNotGate)},
+	{"AndGate", 2, 1, binaryBuilder(NewAndGate), binaryBuilder(NewBehavioralAndGate)},
+	{"OrGate", 2, 1, binaryBuilder(NewOrGate), binaryBuilder(NewBehavioralOrGate)},
+	{"NandGate", 2, 1, binaryBuilder(NewNandGate), binaryBuilder(NewBehavioralNandGate)},
+	{"XorGate", 2, 1, binaryBuilder(NewXorGate), binaryBuilder(NewBehavioralXorGate)},
+	{"SimpleAdder", 2, 2, simpleAdderBuilder(NewSimpleAdder), simpleAdderBuilder(NewBehavioralSimpleAdder)},
+	{"FullAdder", 3, 2, fullAdderBuilder(NewFullAdder), fullAdderBuilder(NewBehavioralFullAdder)},
+	{"AdderSubtractor", 4, 2, adderSubtractorBuilder(NewAdderSubtractor), adderSubtractorBuilder(NewBehavioralAdderSubtractor)},
+}
+
+// Blocks holding state between steps, whose outputs depend on the inputs of
+// previous steps. CheckEquivalence runs a single step, so they are compared
+// over clocked sequences instead
+var SequentialBlocks = []Block{
+	{"DFlipFlop", 2, 1, binaryBuilder(NewDFlipFlop), binaryBuilder(NewBehavioralDFlipFlop)},
+}
+
+func FindBlock(name string) (Block, bool) {
+	for _, block := range append(slices.Clone(Blocks), SequentialBlocks...) {
+		if block.Name == name {
+			return block, true
+		}
+	}
+	return Block{}, false
+}
+
+// Input vector for which two implementations of a block disagree
+type Mismatch struct {
+	Inputs     []NodeState
+	Transistor []NodeState
+	Behavioral []NodeState
+}
+
+func formatStates(states []NodeState) string {
+	values := make([]string, len(states))
+	for i, state := range states {
+		values[i] = state.String()
+	}
+	return strings.Join(values, ", ")
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("Inputs<%s> generated <%s> at transistor level but <%s> behaviorally",
+		formatStates(m.Inputs), formatStates(m.Transistor), formatStates(m.Behavioral))
+}
+
+// Simulates a single instance built by build with the given input states
+func SimulateBuilder(build Builder, inputStates []NodeState, maxDefers int) ([]NodeState, error) {
+	inputs := make([]*Node, len(inputStates))
+	components := make([]Component, 0, len(inputStates)+1)
+	for i, state := range inputStates {
+		inputs[i] = NewNode(fmt.Sprintf("Input%d", i+1))
+		components = append(components, NewInput(inputs[i].ID, inputs[i], state))
+	}
+	outputs, component := build(inputs)
+	components = append(components, component)
+
+	if err := NewCircuit(components, maxDefers, false).Tick(); err != nil {
+		return nil, err
+	}
+	states := make([]NodeState, len(outputs))
+	for i, output := range outputs {
+		states[i] = output.State
+	}
+	return states, nil
+}
+
+func compareVector(block Block, inputs []NodeState, maxDefers int) (*Mismatch, error) {
+	transistor, err := SimulateBuilder(block.Transistor, inputs, maxDefers)
+	if err != nil {
+		return nil, fmt.Errorf("transistor level %s failed for inputs <%s>: %w", block.Name, formatStates(inputs), err)
+	}
+	behavioral, err := SimulateBuilder(block.Behavioral, inputs, maxDefers)
+	if err != nil {
+		return nil, fmt.Errorf("behavioral %s failed for inputs <%s>: %w", block.Name, formatStates(inputs), err)
+	}
+	for i := range transistor {
+		if transistor[i] != behavioral[i] {
+			return &Mismatch{Inputs: inputs, Transistor: transistor, Behavioral: behavioral}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Converts the bits of vector into input states, least significant bit first
+func vectorStates(vector uint64, inputCount int) []NodeState {
+	states := make([]NodeState, inputCount)
+	for i := range states {
+		states[i] = boolToState(vector&(1<<i) != 0)
+	}
+	return states
+}
+
+// Inputs of the widest block whose input vectors are all simulated, about a
+// million of them. Wider blocks take hours, they are sampled instead
+const maxExhaustiveInputs = 20
+
+// Compares the transistor and behavioral implementations of block for every
+// possible input vector. Blocks with too many inputs for that are rejected,
+// CheckEquivalenceSampled checks them instead
+func CheckEquivalence(block Block, maxDefers int) (mismatches []Mismatch, err error) {
+	if block.InputCount > maxExhaustiveInputs {
+		return nil, fmt.Errorf("%s has %d inputs, more than the %d whose vectors can all be checked, sample them with CheckEquivalenceSampled instead",
+			block.Name, block.InputCount, maxExhaustiveInputs)
+	}
+	for vector := uint64(0); vector < 1<<block.InputCount; vector++ {
+		mismatch, err := compareVector(block, vectorStates(vector, block.InputCount), maxDefers)
+		if err != nil {
+			return mismatches, err
+		}
+		if mismatch != nil {
+			mismatches = append(mismatches, *mismatch)
+		}
+	}
+	return mismatches, nil
+}
+
+// Compares the transistor and behavioral implementations of block for
+// samples random input vectors, for blocks too wide to check exhaustively
+func CheckEquivalenceSampled(block Block, maxDefers, samples int, rng *rand.Rand) (mismatches []Mismatch, err error) {
+	for range samples {
+		inputs := make([]NodeState, block.InputCount)
+		for i := range inputs {
+			inputs[i] = boolToState(rng.Intn(2) == 1)
+		}
+		mismatch, err := compareVector(block, inputs, maxDefers)
+		if err != nil {
+			return mismatches, err
+		}
+		if mismatch != nil {
+			mismatches = append(mismatches, *mismatch)
+		}
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,60 @@
+package sim
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestBehavioralModelsMatchTransistorLevel(t *testing.T) {
+	for _, block := range Blocks {
+		mismatches, err := CheckEquivalence(block, 10)
+		if err != nil {
+			t.Errorf("%s: %s", block.Name, err.Error())
+		}
+		for _, mismatch := range mismatches {
+			t.Errorf("%s: %s", block.Name, mismatch)
+		}
+	}
+}
+
+func TestSampledEquivalence(t *testing.T) {
+	block, ok := FindBlock("AdderSubtractor")
+	if !ok {
+		t.Fatalf("AdderSubtractor block not registered")
+	}
+	mismatches, err := CheckEquivalenceSampled(block, 10, 8, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Error(err)
+	}
+	for _, mismatch := range mismatches {
+		t.Errorf("%s", mismatch)
+	}
+}
+
+func TestEquivalenceReportsMismatches(t *testing.T) {
+	block, _ := FindBlock("AndGate")
+	block.Behavioral = binaryBuilder(NewBehavioralOrGate)
+
+	mismatches, err := CheckEquivalence(block, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 2 {
+		t.Fatalf("expected 2 mismatches but got %d: %v", len(mismatches), mismatches)
+	}
+	for _, mismatch := range mismatches {
+		if mismatch.Transistor[0] != Off || mismatch.Behavioral[0] != On {
+			t.Errorf("unexpected mismatch %s", mismatch)
+		}
+	}
+}
+
+func TestEquivalenceRejectsWideBlocks(t *testing.T) {
+	block, _ := FindBlock("AndGate")
+	block.InputCount = maxExhaustiveInputs + 1
+	_, err := CheckEquivalence(block, 10)
+	if err == nil || !strings.Contains(err.Error(), "CheckEquivalenceSampled") {
+		t.Errorf("expected a block with %d inputs to be rejected in favour of sampling but got %v", block.InputCount, err)
+	}
+}
@@ -0,0 +1,100 @@
+package sim
+
+import "fmt"
+
+// Edge triggered D flip-flop. Nodes are reset on every step, so the stored
+// bit lives in the component: Q is driven with the stored bit, and on a clock
+// edge the bit is replaced by the data sampled at the end of the previous step
+type FlipFlop struct {
+	ComponentID
+	Clock *Node
+	Data  *Node
+	Q     *Node
+
+	RisingEdge bool
+	stored     NodeState
+	lastClock  NodeState
+	lastData   NodeState
+}
+
+func NewFlipFlop(name string, clock, data *Node, risingEdge bool) (*Node, *FlipFlop) {
+	f := &FlipFlop{
+		Clock:      NewNode(fmt.Sprintf("%s-Clock", name)).Connect(clock),
+		Data:       NewNode(fmt.Sprintf("%s-Data", name)).Connect(data),
+		Q:          NewNode(fmt.Sprintf("%s-Q", name)),
+		RisingEdge: risingEdge,
+		stored:     Off,
+		lastClock:  Undefined,
+		lastData:   Undefined,
+	}
+	f.ComponentID.Name = name
+	f.Clock.Parent = f
+	f.Data.Parent = f
+	f.Q.Parent = f
+	return f.Q, f
+}
+
+// Rising edge triggered flip-flop of the DFlipFlop block
+func NewBehavioralDFlipFlop(clock, data *Node) (*Node, *FlipFlop) {
+	return NewFlipFlop("DFlipFlop", clock, data, true)
+}
+
+// Sets the stored bit, as done by an initial value or a reset
+func (f *FlipFlop) Set(state NodeState) {
+	f.stored = state
+}
+
+func (f *FlipFlop) Stored() NodeState {
+	return f.stored
+}
+
+func (f *FlipFlop) Reset() {
+	f.Clock.Reset()
+	f.Data.Reset()
+	f.Q.Reset()
+}
+
+func (f *FlipFlop) Ready() bool {
+	return f.Clock.State != Undefined
+}
+
+func (f *FlipFlop) Act() error {
+	if !f.Ready() {
+		return fmt.Errorf("component %s was executed before it was ready", f.Debug())
+	}
+	active, inactive := NodeState(On), NodeState(Off)
+	if !f.RisingEdge {
+		active, inactive = Off, On
+	}
+	if f.Clock.State == active && f.lastClock == inactive && f.lastData != Undefined {
+		f.stored = f.lastData
+	}
+	return f.Q.Change(f.stored)
+}
+
+// Samples clock and data once the step has settled
+func (f *FlipFlop) Latch() {
+	f.lastClock = f.Clock.State
+	f.lastData = f.Data.State
+}
+
+func (f *FlipFlop) GetID() ComponentID {
+	return f.ComponentID
+}
+
+func (f *FlipFlop) GetPosition() (int32, int32) {
+	return f.Position.Unpack()
+}
+
+func (f *FlipFlop) Nodes() []*Node {
+	return []*Node{f.Clock, f.Data, f.Q}
+}
+
+func (f *FlipFlop) Clone(overrides ComponentID) Component {
+	// TODO
+	return f
+}
+
+func (f *FlipFlop) Debug() string {
+	return fmt.Sprintf("FlipFlop<clock: %s, data: %s, q: %s, stored: %s>", f.Clock.Debug(), f.Data.Debug(), f.Q.Debug(), f.stored)
+}
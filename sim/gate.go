@@ -1,4 +1,4 @@
-package main
+package sim
 
 import "fmt"
 
@@ -26,6 +26,7 @@ func NewNotGate(input *Node) (*Node, *CustomComponent) {
 			NewTransistor(parent, outputNode, input, SharedGroundNode),
 		},
 		[]*Node{input},
+		[]*Node{outputNode},
 	)
 }
 
@@ -59,6 +60,7 @@ func NewAndGate(input1, input2 *Node) (*Node, *CustomComponent) {
 			NewResistor(parent, outputNode, SharedGroundNode),
 		},
 		[]*Node{input1, input2},
+		[]*Node{outputNode},
 	)
 }
 
@@ -89,6 +91,7 @@ func NewOrGate(input1, input2 *Node) (*Node, *CustomComponent) {
 			NewResistor(parent, outputNode, SharedGroundNode),
 		},
 		[]*Node{input1, input2},
+		[]*Node{outputNode},
 	)
 }
 
@@ -122,6 +125,7 @@ func NewNandGate(input1, input2 *Node) (*Node, *CustomComponent) {
 			NewResistor(parent, SharedSourceNode, outputNode),
 		},
 		[]*Node{input1, input2},
+		[]*Node{outputNode},
 	)
 }
 
@@ -135,5 +139,45 @@ func NewXorGate(input1, input2 *Node) (*Node, *CustomComponent) {
 		"XorGate",
 		[]Component{orComponent, nandComponent, andComponent},
 		[]*Node{input1, input2},
+		[]*Node{outputNode},
+	)
+}
+
+// gated D latch, output follows data while enable is on and holds its state
+// otherwise
+//
+//	set   = data NAND enable
+//	reset = set NAND enable
+//	outputInverted = reset NAND output
+//	output = set NAND outputInverted
+//
+// The gate driving outputInverted comes first, so that a latch powered on
+// holding nothing is seeded with output off
+func NewDLatch(data, enable *Node) (*Node, *CustomComponent) {
+	setNode, setGate := NewNandGate(data, enable)
+	resetNode, resetGate := NewNandGate(setNode, enable)
+	outputNode := NewNode("DLatchOutput")
+	invertedNode, invertedGate := NewNandGate(resetNode, outputNode)
+	nandOutput, outputGate := NewNandGate(setNode, invertedNode)
+	outputNode.Connect(nandOutput)
+	return outputNode, NewCustomComponent(
+		"DLatch",
+		[]Component{setGate, resetGate, invertedGate, outputGate},
+		[]*Node{data, enable},
+		[]*Node{outputNode},
+	)
+}
+
+// rising edge triggered D flip-flop, a master latch open while clock is off
+// feeding a slave latch open while clock is on
+func NewDFlipFlop(clock, data *Node) (*Node, *CustomComponent) {
+	enable, notGate := NewNotGate(clock)
+	masterOutput, master := NewDLatch(data, enable)
+	outputNode, slave := NewDLatch(masterOutput, clock)
+	return outputNode, NewCustomComponent(
+		"DFlipFlop",
+		[]Component{notGate, master, slave},
+		[]*Node{clock, data},
+		[]*Node{outputNode},
 	)
 }
@@ -1,4 +1,4 @@
-package main
+package sim
 
 import "testing"
 
@@ -13,7 +13,7 @@ func TestNotGate(t *testing.T) {
 	for _, tc := range tt {
 		components := []Component{}
 		input := NewNode("Input")
-		inputComponents := []Component{NewInput(input, tc.input)}
+		inputComponents := []Component{NewInput("input", input, tc.input)}
 		components = append(components, inputComponents...)
 
 		notOutput, notGate := NewNotGate(input)
@@ -21,7 +21,7 @@ func TestNotGate(t *testing.T) {
 
 		c := NewCircuit(components, 4, true)
 		if err := c.Tick(); err != nil {
-			t.Errorf(err.Error())
+			t.Error(err)
 		}
 		if notOutput.State != tc.expectedOutput {
 			t.Errorf("input: %s generated output state %s instead of %s",
@@ -46,8 +46,8 @@ func TestAndGate(t *testing.T) {
 		input1 := NewNode("Input1")
 		input2 := NewNode("Input2")
 		inputComponents := []Component{
-			NewInput(input1, tc.input1),
-			NewInput(input2, tc.input2),
+			NewInput("input1", input1, tc.input1),
+			NewInput("input2", input2, tc.input2),
 		}
 		components = append(components, inputComponents...)
 
@@ -56,7 +56,7 @@ func TestAndGate(t *testing.T) {
 
 		c := NewCircuit(components, 4, false)
 		if err := c.Tick(); err != nil {
-			t.Errorf(err.Error())
+			t.Error(err)
 		}
 		if andOutput.State != tc.expectedOutput {
 			t.Errorf("Inputs<input1: %s, input2: %s> generated output state %s instead of %s",
@@ -81,8 +81,8 @@ func TestOrGate(t *testing.T) {
 		input1 := NewNode("Input1")
 		input2 := NewNode("Input2")
 		inputComponents := []Component{
-			NewInput(input1, tc.input1),
-			NewInput(input2, tc.input2),
+			NewInput("input1", input1, tc.input1),
+			NewInput("input2", input2, tc.input2),
 		}
 		components = append(components, inputComponents...)
 
@@ -91,7 +91,7 @@ func TestOrGate(t *testing.T) {
 
 		c := NewCircuit(components, 4, false)
 		if err := c.Tick(); err != nil {
-			t.Errorf(err.Error())
+			t.Error(err)
 		}
 		if orOutput.State != tc.expectedOutput {
 			t.Errorf("Inputs<input1: %s, input2: %s> generated output state %s instead of %s",
@@ -116,8 +116,8 @@ func TestNandGate(t *testing.T) {
 		input1 := NewNode("Input1")
 		input2 := NewNode("Input2")
 		inputComponents := []Component{
-			NewInput(input1, tc.input1),
-			NewInput(input2, tc.input2),
+			NewInput("input1", input1, tc.input1),
+			NewInput("input2", input2, tc.input2),
 		}
 		components = append(components, inputComponents...)
 
@@ -126,7 +126,7 @@ func TestNandGate(t *testing.T) {
 
 		c := NewCircuit(components, 4, false)
 		if err := c.Tick(); err != nil {
-			t.Errorf(err.Error())
+			t.Error(err)
 		}
 		if nandOutput.State != tc.expectedOutput {
 			t.Errorf("Inputs<input1: %s, input2: %s> generated output state %s instead of %s",
@@ -151,8 +151,8 @@ func TestXorGate(t *testing.T) {
 		input1 := NewNode("Input1")
 		input2 := NewNode("Input2")
 		inputComponents := []Component{
-			NewInput(input1, tc.input1),
-			NewInput(input2, tc.input2),
+			NewInput("input1", input1, tc.input1),
+			NewInput("input2", input2, tc.input2),
 		}
 		components = append(components, inputComponents...)
 
@@ -161,7 +161,7 @@ func TestXorGate(t *testing.T) {
 
 		c := NewCircuit(components, 4, false)
 		if err := c.Tick(); err != nil {
-			t.Errorf(err.Error())
+			t.Error(err)
 		}
 		if xorOutput.State != tc.expectedOutput {
 			t.Errorf("Inputs<input1: %s, input2: %s> generated output state %s instead of %s",
@@ -172,7 +172,7 @@ func TestXorGate(t *testing.T) {
 
 func TestChainingGates(t *testing.T) {
 	input := NewNode("Input")
-	components := []Component{NewInput(input, On)}
+	components := []Component{NewInput("input", input, On)}
 
 	notOut, notGate := NewNotGate(input)
 	xorOut, xorGate := NewNandGate(input, notOut)
@@ -183,7 +183,7 @@ func TestChainingGates(t *testing.T) {
 
 	c := NewCircuit(components, 10, false)
 	if err := c.Tick(); err != nil {
-		t.Errorf(err.Error())
+		t.Error(err)
 	}
 	if notOut.State != Off {
 		t.Errorf("expected NOT gate to output off, but got %s", notOut.State)
@@ -201,3 +201,75 @@ func TestChainingGates(t *testing.T) {
 		t.Errorf("expected NAND gate to output off, but got %s", nandOut.State)
 	}
 }
+
+func TestDLatch(t *testing.T) {
+	data := NewNode("Data")
+	enable := NewNode("Enable")
+	dataInput := NewInput("data", data, Off)
+	enableInput := NewInput("enable", enable, Off)
+	output, latch := NewDLatch(data, enable)
+	c := NewCircuit([]Component{dataInput, enableInput, latch}, 4, false)
+
+	tt := []struct {
+		data, enable   NodeState
+		expectedOutput NodeState
+	}{
+		// powered on holding off
+		{data: On, enable: Off, expectedOutput: Off},
+		{data: On, enable: On, expectedOutput: On},
+		{data: Off, enable: Off, expectedOutput: On},
+		{data: Off, enable: On, expectedOutput: Off},
+		{data: On, enable: Off, expectedOutput: Off},
+	}
+	for i, tc := range tt {
+		dataInput.SetState(tc.data)
+		enableInput.SetState(tc.enable)
+		if err := c.Tick(); err != nil {
+			t.Fatal(err)
+		}
+		if output.State != tc.expectedOutput {
+			t.Errorf("step %d: data %s and enable %s generated output state %s instead of %s",
+				i, tc.data, tc.enable, output.State, tc.expectedOutput)
+		}
+	}
+}
+
+func TestDFlipFlop(t *testing.T) {
+	block, ok := FindBlock("DFlipFlop")
+	if !ok {
+		t.Fatalf("DFlipFlop block not registered")
+	}
+	tt := []struct {
+		clock, data    NodeState
+		expectedOutput NodeState
+	}{
+		// powered on holding off
+		{clock: Off, data: On, expectedOutput: Off},
+		// rising edge, storing the data of the step before
+		{clock: On, data: On, expectedOutput: On},
+		{clock: On, data: Off, expectedOutput: On},
+		{clock: Off, data: Off, expectedOutput: On},
+		{clock: On, data: On, expectedOutput: Off},
+		{clock: Off, data: On, expectedOutput: Off},
+		{clock: On, data: Off, expectedOutput: On},
+	}
+	for _, level := range []AbstractionLevel{LevelTransistor, LevelBehavioral} {
+		clock := NewNode("Clock")
+		data := NewNode("Data")
+		clockInput := NewInput("clock", clock, Off)
+		dataInput := NewInput("data", data, Off)
+		instance := NewBlockInstance("flipflop", block, []*Node{clock, data}, level)
+		c := NewCircuit([]Component{clockInput, dataInput, instance}, 4, false)
+		for i, tc := range tt {
+			clockInput.SetState(tc.clock)
+			dataInput.SetState(tc.data)
+			if err := c.Tick(); err != nil {
+				t.Fatal(err)
+			}
+			if instance.Outputs[0].State != tc.expectedOutput {
+				t.Errorf("%s step %d: clock %s and data %s generated output state %s instead of %s",
+					level, i, tc.clock, tc.data, instance.Outputs[0].State, tc.expectedOutput)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package sim
+
+import "fmt"
+
+// Entry of the component hierarchy, children are the components nested in it
+type HierarchyEntry struct {
+	Component   Component
+	Label       string
+	Transistors int
+	Children    []*HierarchyEntry
+}
+
+// Returns the components nested inside c, block instances expose the
+// implementation for their current level
+func Subcomponents(c Component) []Component {
+	switch c := c.(type) {
+	case *CustomComponent:
+		return c.Subcomponents
+	case *BlockInstance:
+		return []Component{c.implementation(c.Level).component}
+	}
+	return nil
+}
+
+// Returns the input and output nodes of c, as seen from its parent
+func Ports(c Component) (inputs, outputs []*Node) {
+	switch c := c.(type) {
+	case *CustomComponent:
+		return c.Inputs, c.Outputs
+	case *FunctionComponent:
+		return c.Inputs, c.Outputs
+	case *BlockInstance:
+		return c.Inputs, c.Outputs
+	case *FlipFlop:
+		return []*Node{c.Clock, c.Data}, []*Node{c.Q}
+	}
+	return c.Nodes(), nil
+}
+
+// Name a component is known by, terminals and meters built without a name go
+// by the ID of their node
+func ComponentName(c Component) string {
+	switch c := c.(type) {
+	case *Terminal:
+		return componentName(c.ComponentID, c.Node)
+	case *Meter:
+		return componentName(c.ComponentID, c.Node)
+	}
+	if name := c.GetID().Name; name != "" {
+		return name
+	}
+	return ComponentLabel(c)
+}
+
+func ComponentLabel(c Component) string {
+	switch c := c.(type) {
+	case *CustomComponent:
+		return c.ComponentType
+	case *FunctionComponent:
+		return c.ComponentType + " (behavioral)"
+	case *BlockInstance:
+		return fmt.Sprintf("%s [%s, %s]", c.Name, c.Block.Name, c.Level)
+	case *Terminal:
+		if c.Name != "" {
+			return c.Name
+		}
+		return c.terminalType
+	case *Meter:
+		if c.Name != "" {
+			return c.Name
+		}
+		return "Multimeter"
+	case *Resistor:
+		if c.Name != "" {
+			return c.Name
+		}
+		return "Resistor"
+	case *Transistor:
+		if c.Name != "" {
+			return c.Name
+		}
+		return "Transistor"
+	}
+	return c.GetID().Name
+}
+
+func CountTransistors(c Component) int {
+	if _, ok := c.(*Transistor); ok {
+		return 1
+	}
+	count := 0
+	for _, subcomponent := range Subcomponents(c) {
+		count += CountTransistors(subcomponent)
+	}
+	return count
+}
+
+func BuildHierarchy(components []Component) []*HierarchyEntry {
+	entries := make([]*HierarchyEntry, len(components))
+	for i, component := range components {
+		entries[i] = &HierarchyEntry{
+			Component:   component,
+			Label:       ComponentLabel(component),
+			Transistors: CountTransistors(component),
+			Children:    BuildHierarchy(Subcomponents(component)),
+		}
+	}
+	return entries
+}
+
+type HierarchyRow struct {
+	Entry *HierarchyEntry
+	Depth int32
+}
+
+// Flattens the hierarchy into the rows visible on the panel
+func VisibleHierarchyRows(entries []*HierarchyEntry, expanded map[Component]bool, depth int32) (rows []HierarchyRow) {
+	for _, entry := range entries {
+		rows = append(rows, HierarchyRow{entry, depth})
+		if expanded[entry.Component] {
+			rows = append(rows, VisibleHierarchyRows(entry.Children, expanded, depth+1)...)
+		}
+	}
+	return
+}
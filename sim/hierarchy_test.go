@@ -0,0 +1,59 @@
+package sim
+
+import "testing"
+
+func TestCountTransistors(t *testing.T) {
+	tt := []struct {
+		block       string
+		transistors int
+	}{
+		{block: "NotGate", transistors: 1},
+		{block: "AndGate", transistors: 2},
+		{block: "XorGate", transistors: 6},
+		{block: "SimpleAdder", transistors: 8},
+		{block: "FullAdder", transistors: 22},
+		{block: "AdderSubtractor", transistors: 28},
+	}
+	for _, tc := range tt {
+		block, _ := FindBlock(tc.block)
+		instance := NewBlockInstance(tc.block, block, nil, LevelTransistor)
+		if count := CountTransistors(instance); count != tc.transistors {
+			t.Errorf("%s has %d transistors instead of %d", tc.block, count, tc.transistors)
+		}
+		instance.SetLevel(LevelBehavioral)
+		if count := CountTransistors(instance); count != 0 {
+			t.Errorf("behavioral %s has %d transistors instead of 0", tc.block, count)
+		}
+	}
+}
+
+func TestBuildHierarchy(t *testing.T) {
+	input1 := NewNode("Input1")
+	input2 := NewNode("Input2")
+	_, xorGate := NewXorGate(input1, input2)
+
+	entries := BuildHierarchy([]Component{xorGate})
+	if len(entries) != 1 || entries[0].Label != "XorGate" {
+		t.Fatalf("expected a single XorGate entry but got %v", entries)
+	}
+	expected := []string{"OrGate", "NandGate", "AndGate"}
+	if len(entries[0].Children) != len(expected) {
+		t.Fatalf("expected %d children but got %d", len(expected), len(entries[0].Children))
+	}
+	for i, child := range entries[0].Children {
+		if child.Label != expected[i] || child.Transistors != 2 {
+			t.Errorf("child %d is %s with %d transistors instead of %s with 2",
+				i, child.Label, child.Transistors, expected[i])
+		}
+		for _, leaf := range child.Children {
+			if len(leaf.Children) != 0 {
+				t.Errorf("primitive %s should not have children", leaf.Label)
+			}
+		}
+	}
+
+	rows := VisibleHierarchyRows(entries, map[Component]bool{xorGate: true}, 0)
+	if len(rows) != 4 {
+		t.Errorf("expanding XorGate should show 4 rows but got %d", len(rows))
+	}
+}
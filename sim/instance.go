@@ -0,0 +1,170 @@
+package sim
+
+import (
+	"fmt"
+)
+
+// Level at which a block instance is simulated
+type AbstractionLevel int
+
+const (
+	LevelTransistor AbstractionLevel = iota
+	LevelBehavioral
+)
+
+func (l AbstractionLevel) String() string {
+	switch l {
+	case LevelTransistor:
+		return "transistor"
+	case LevelBehavioral:
+		return "behavioral"
+	default:
+		return "unknown"
+	}
+}
+
+// Instance of a Block whose abstraction level can be switched without
+// touching its connections. Inputs and Outputs are owned by the instance, so
+// the rest of the circuit is always connected to the same boundary nodes
+type BlockInstance struct {
+	ComponentID
+	Block   Block
+	Level   AbstractionLevel
+	Inputs  []*Node
+	Outputs []*Node
+
+	implementations map[AbstractionLevel]*blockImplementation
+}
+
+type blockImplementation struct {
+	outputs   []*Node
+	component Component
+}
+
+func NewBlockInstance(name string, block Block, inputs []*Node, level AbstractionLevel) *BlockInstance {
+	b := &BlockInstance{
+		Block:           block,
+		Level:           level,
+		implementations: map[AbstractionLevel]*blockImplementation{},
+	}
+	b.ComponentID.Name = name
+	for i := range block.InputCount {
+		var input *Node
+		if i < len(inputs) {
+			input = inputs[i]
+		}
+		node := NewNode(fmt.Sprintf("%s-Input%d", name, i+1)).Connect(input)
+		node.Parent = b
+		node.OffsetY = float32(i+1) / float32(block.InputCount+1)
+		b.Inputs = append(b.Inputs, node)
+	}
+	for i := range block.OutputCount {
+		node := NewNode(fmt.Sprintf("%s-Output%d", name, i+1))
+		node.Parent = b
+		node.OffsetX = 1.0
+		node.OffsetY = float32(i+1) / float32(block.OutputCount+1)
+		b.Outputs = append(b.Outputs, node)
+	}
+	b.implementation(level).connect(b.Outputs)
+	return b
+}
+
+// Builds the implementation for level on first use and caches it, so
+// switching back and forth keeps the internal nodes of each level
+func (b *BlockInstance) implementation(level AbstractionLevel) *blockImplementation {
+	if impl, ok := b.implementations[level]; ok {
+		return impl
+	}
+	build := b.Block.Transistor
+	if level == LevelBehavioral {
+		build = b.Block.Behavioral
+	}
+	outputs, component := build(b.Inputs)
+	impl := &blockImplementation{outputs: outputs, component: component}
+	b.implementations[level] = impl
+	return impl
+}
+
+func (i *blockImplementation) connect(ports []*Node) {
+	for index, output := range i.outputs {
+		ports[index].Connect(output)
+	}
+}
+
+func (i *blockImplementation) disconnect(ports []*Node) {
+	for index, output := range i.outputs {
+		ports[index].Disconnect(output)
+	}
+}
+
+func (b *BlockInstance) SetLevel(level AbstractionLevel) {
+	if level == b.Level {
+		return
+	}
+	b.implementation(b.Level).disconnect(b.Outputs)
+	b.implementation(level).connect(b.Outputs)
+	b.Level = level
+}
+
+func (b *BlockInstance) ToggleLevel() {
+	if b.Level == LevelTransistor {
+		b.SetLevel(LevelBehavioral)
+	} else {
+		b.SetLevel(LevelTransistor)
+	}
+}
+
+func (b *BlockInstance) Reset() {
+	b.implementation(b.Level).component.Reset()
+	for _, node := range b.Nodes() {
+		node.Reset()
+	}
+}
+
+func (b *BlockInstance) Ready() bool {
+	for _, input := range b.Inputs {
+		if input.State == Undefined {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *BlockInstance) Act() error {
+	return b.actIn(nil)
+}
+
+func (b *BlockInstance) actIn(r *run) error {
+	if !b.Ready() {
+		return fmt.Errorf("component %s was executed before it was ready", b.Debug())
+	}
+	return act(b.implementation(b.Level).component, r)
+}
+
+func (b *BlockInstance) GetID() ComponentID {
+	return b.ComponentID
+}
+
+func (b *BlockInstance) GetPosition() (int32, int32) {
+	return b.Position.Unpack()
+}
+
+func (b *BlockInstance) Nodes() []*Node {
+	return append(append([]*Node{}, b.Inputs...), b.Outputs...)
+}
+
+func (b *BlockInstance) Clone(newID ComponentID) Component {
+	newInstance := NewBlockInstance(newID.Name, b.Block, nil, b.Level)
+	newInstance.ComponentID = newID
+	// keep the node offsets of oriented instances
+	newNodes := newInstance.Nodes()
+	for i, node := range b.Nodes() {
+		newNodes[i].OffsetX, newNodes[i].OffsetY = node.OffsetX, node.OffsetY
+	}
+	return newInstance
+}
+
+func (b *BlockInstance) Debug() string {
+	return fmt.Sprintf("%s<name: %s, level: %s>\n%s",
+		b.Block.Name, b.Name, b.Level, b.implementation(b.Level).component.Debug())
+}
@@ -0,0 +1,102 @@
+package sim
+
+import (
+	"fmt"
+	"testing"
+)
+
+// builds a ripple carry adder out of FullAdder instances, returning the sum
+// bits followed by the carry out
+func newRippleCarryAdder(a, b []*Node, carryIn *Node) (outputs []*Node, components []Component) {
+	block, _ := FindBlock("FullAdder")
+	carry := carryIn
+	for i := range a {
+		adder := NewBlockInstance(fmt.Sprintf("fa%d", i), block, []*Node{a[i], b[i], carry}, LevelTransistor)
+		outputs = append(outputs, adder.Outputs[0])
+		carry = adder.Outputs[1]
+		components = append(components, adder)
+	}
+	outputs = append(outputs, carry)
+	return
+}
+
+func TestMixedLevelRippleCarryAdder(t *testing.T) {
+	bits := 2
+	for levels := range 1 << bits {
+		for a := range 1 << bits {
+			for b := range 1 << bits {
+				inputsA := make([]*Node, bits)
+				inputsB := make([]*Node, bits)
+				components := []Component{}
+				for i := range bits {
+					inputsA[i] = NewNode(fmt.Sprintf("A%d", i))
+					inputsB[i] = NewNode(fmt.Sprintf("B%d", i))
+					components = append(components,
+						NewInput(inputsA[i].ID, inputsA[i], boolToState(a&(1<<i) != 0)),
+						NewInput(inputsB[i].ID, inputsB[i], boolToState(b&(1<<i) != 0)),
+					)
+				}
+				carryIn := NewNode("CarryIn")
+				components = append(components, NewInput("CarryIn", carryIn, Off))
+				outputs, adders := newRippleCarryAdder(inputsA, inputsB, carryIn)
+				components = append(components, adders...)
+
+				c := NewCircuit(components, 10, false)
+				for i := range bits {
+					level := LevelTransistor
+					if levels&(1<<i) != 0 {
+						level = LevelBehavioral
+					}
+					if err := c.SetLevel(fmt.Sprintf("fa%d", i), level); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := c.Tick(); err != nil {
+					t.Fatal(err)
+				}
+
+				sum := 0
+				for i, output := range outputs {
+					switch output.State {
+					case On:
+						sum |= 1 << i
+					case Undefined:
+						t.Fatalf("levels %b: output %d of %d + %d is undefined", levels, i, a, b)
+					}
+				}
+				if sum != a+b {
+					t.Errorf("levels %b: %d + %d generated %d", levels, a, b, sum)
+				}
+			}
+		}
+	}
+}
+
+func TestSwitchingLevelKeepsConnections(t *testing.T) {
+	block, _ := FindBlock("XorGate")
+	input1 := NewNode("Input1")
+	input2 := NewNode("Input2")
+	xor := NewBlockInstance("xor", block, []*Node{input1, input2}, LevelTransistor)
+	c := NewCircuit([]Component{
+		NewInput("Input1", input1, On),
+		NewInput("Input2", input2, Off),
+		xor,
+	}, 10, false)
+
+	for _, level := range []AbstractionLevel{LevelTransistor, LevelBehavioral, LevelTransistor} {
+		xor.SetLevel(level)
+		if err := c.Tick(); err != nil {
+			t.Fatal(err)
+		}
+		if xor.Outputs[0].State != On {
+			t.Errorf("%s level generated %s instead of on", level, xor.Outputs[0].State)
+		}
+	}
+}
+
+func TestSetLevelUnknownInstance(t *testing.T) {
+	c := NewCircuit([]Component{}, 10, false)
+	if err := c.SetLevel("missing", LevelBehavioral); err == nil {
+		t.Errorf("expected an error when setting the level of a missing instance")
+	}
+}
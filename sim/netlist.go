@@ -0,0 +1,543 @@
+package sim
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Design imported from a gate-level netlist or a circuit description, mapped
+// onto the transistor-level gate builders
+type Netlist struct {
+	Name       string
+	Inputs     []string
+	Outputs    []string
+	Nets       map[string]*Node
+	Terminals  map[string]*Terminal
+	Meters     map[string]*Meter
+	Components []Component
+	Circuit    *Circuit
+
+	// nodes tied to a constant state, by state
+	constants map[NodeState]*Node
+}
+
+func newNetlist() *Netlist {
+	return &Netlist{
+		Nets:      map[string]*Node{},
+		Terminals: map[string]*Terminal{},
+		Meters:    map[string]*Meter{},
+		constants: map[NodeState]*Node{},
+	}
+}
+
+// Node held at state by a terminal of the netlist. Constants are not tied to
+// the shared supply nodes, which would join the nets of every netlist loaded
+func (n *Netlist) constant(state NodeState) *Node {
+	if node, ok := n.constants[state]; ok {
+		return node
+	}
+	var node *Node
+	if state == On {
+		node = NewNode("vdd")
+		n.Components = append(n.Components, NewSource("vdd", node))
+	} else {
+		node = NewNode("gnd")
+		n.Components = append(n.Components, NewGround("gnd", node))
+	}
+	n.constants[state] = node
+	return node
+}
+
+func (n *Netlist) net(name string) *Node {
+	if node, ok := n.Nets[name]; ok {
+		return node
+	}
+	node := NewNode(name)
+	n.Nets[name] = node
+	return node
+}
+
+func (n *Netlist) nets(names []string) []*Node {
+	nodes := make([]*Node, len(names))
+	for i, name := range names {
+		nodes[i] = n.net(name)
+	}
+	return nodes
+}
+
+// Drives the net named output with node
+func (n *Netlist) drive(output string, node *Node) {
+	n.net(output).Connect(node)
+}
+
+// Chains two input builders to support gates with any number of inputs
+func (n *Netlist) chain(inputs []*Node, build func(input1, input2 *Node) (*Node, *CustomComponent)) *Node {
+	out := inputs[0]
+	for _, input := range inputs[1:] {
+		var component *CustomComponent
+		out, component = build(out, input)
+		n.Components = append(n.Components, component)
+	}
+	return out
+}
+
+func (n *Netlist) not(input *Node) *Node {
+	out, component := NewNotGate(input)
+	n.Components = append(n.Components, component)
+	return out
+}
+
+// Instantiates a gate primitive, kind is one of the Verilog gate primitives
+func (n *Netlist) gate(kind string, output string, inputs []string) error {
+	if len(inputs) == 0 {
+		return fmt.Errorf("gate %s driving %s has no inputs", kind, output)
+	}
+	if kind != "buf" && kind != "not" && len(inputs) < 2 {
+		return fmt.Errorf("gate %s driving %s needs at least two inputs", kind, output)
+	}
+	nodes := n.nets(inputs)
+	var out *Node
+	switch kind {
+	case "buf":
+		out = nodes[0]
+	case "not":
+		out = n.not(nodes[0])
+	case "and":
+		out = n.chain(nodes, NewAndGate)
+	case "or":
+		out = n.chain(nodes, NewOrGate)
+	case "xor":
+		out = n.chain(nodes, NewXorGate)
+	case "nand":
+		if len(nodes) == 2 {
+			var component *CustomComponent
+			out, component = NewNandGate(nodes[0], nodes[1])
+			n.Components = append(n.Components, component)
+		} else {
+			out = n.not(n.chain(nodes, NewAndGate))
+		}
+	case "nor":
+		out = n.not(n.chain(nodes, NewOrGate))
+	case "xnor":
+		out = n.not(n.chain(nodes, NewXorGate))
+	default:
+		return fmt.Errorf("unsupported gate %s", kind)
+	}
+	n.drive(output, out)
+	return nil
+}
+
+// Adds the terminals and meters of the primary inputs and outputs and builds
+// the circuit
+func (n *Netlist) finish() *Netlist {
+	components := []Component{}
+	for _, input := range n.Inputs {
+		terminal := NewInput(input, n.net(input), Off)
+		n.Terminals[input] = terminal
+		components = append(components, terminal)
+	}
+	components = append(components, n.Components...)
+	for _, output := range n.Outputs {
+		meter := NewMultimeter(output, n.net(output))
+		n.Meters[output] = meter
+		components = append(components, meter)
+	}
+	n.Circuit = NewCircuit(components, len(n.Components)+MAX_DEFERS, false)
+	return n
+}
+
+// Name of a terminal or meter, components built without a name are known
+// by the ID of their node
+func componentName(id ComponentID, node *Node) string {
+	if id.Name != "" {
+		return id.Name
+	}
+	return strings.TrimSuffix(node.ID, "-Node")
+}
+
+// Netlist driven by the input terminals and observed by the meters of
+// already built components, such as the ones of a schematic
+func NewNetlist(name string, components []Component) *Netlist {
+	n := newNetlist()
+	n.Name = name
+	for _, component := range components {
+		switch c := component.(type) {
+		case *Terminal:
+			if c.terminalType == "Input" {
+				name := componentName(c.ComponentID, c.Node)
+				n.Inputs = append(n.Inputs, name)
+				n.Terminals[name] = c
+				n.Nets[name] = c.Node
+			}
+		case *Meter:
+			name := componentName(c.ComponentID, c.Node)
+			n.Outputs = append(n.Outputs, name)
+			n.Meters[name] = c
+			n.Nets[name] = c.Node
+		}
+	}
+	n.Components = components
+	n.Circuit = NewCircuit(components, len(components)+MAX_DEFERS, false)
+	return n
+}
+
+func (n *Netlist) SetInput(name string, state NodeState) error {
+	terminal, ok := n.Terminals[name]
+	if !ok {
+		return fmt.Errorf("netlist has no input %s", name)
+	}
+	terminal.state = state
+	return nil
+}
+
+func (n *Netlist) Output(name string) (NodeState, error) {
+	meter, ok := n.Meters[name]
+	if !ok {
+		return Undefined, fmt.Errorf("netlist has no output %s", name)
+	}
+	return meter.Node.State, nil
+}
+
+// Logic functions of two inputs with a dedicated gate, indexed by their
+// truth table where bit i is the output for input1 = i&1 and input2 = i>>1
+var twoInputGates = map[int]string{
+	0b1000: "and",
+	0b1110: "or",
+	0b0111: "nand",
+	0b0110: "xor",
+	0b0001: "nor",
+	0b1001: "xnor",
+}
+
+// Maps a BLIF .names cover onto gates. Two input functions map onto a single
+// gate, anything else is built as a sum of products of the cover cubes
+func (n *Netlist) names(inputs []string, output string, cubes []string, onSet bool) error {
+	if len(inputs) == 2 {
+		table := 0
+		for i := range 4 {
+			if coverMatches(cubes, []byte{"01"[i&1], "01"[i>>1]}) == onSet {
+				table |= 1 << i
+			}
+		}
+		if kind, ok := twoInputGates[table]; ok {
+			return n.gate(kind, output, inputs)
+		}
+	}
+
+	nodes := n.nets(inputs)
+	var products []*Node
+	for _, cube := range cubes {
+		var literals []*Node
+		for i, literal := range cube {
+			switch literal {
+			case '1':
+				literals = append(literals, nodes[i])
+			case '0':
+				literals = append(literals, n.not(nodes[i]))
+			}
+		}
+		if len(literals) == 0 {
+			// a cube without literals covers every input vector
+			literals = append(literals, n.constant(On))
+		}
+		products = append(products, n.chain(literals, NewAndGate))
+	}
+	var out *Node
+	if len(products) == 0 {
+		// an empty cover is constant zero, regardless of its phase
+		out = n.constant(Off)
+		onSet = true
+	} else {
+		out = n.chain(products, NewOrGate)
+	}
+	if !onSet {
+		out = n.not(out)
+	}
+	n.drive(output, out)
+	return nil
+}
+
+func coverMatches(cubes []string, vector []byte) bool {
+	for _, cube := range cubes {
+		matches := true
+		for i := range cube {
+			if cube[i] != '-' && cube[i] != vector[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// Reads BLIF lines, joining continuations and dropping comments
+func blifLines(r io.Reader) ([]string, []int, error) {
+	var lines []string
+	var numbers []int
+	scanner := bufio.NewScanner(r)
+	pending := ""
+	pendingLine := 0
+	for number := 1; scanner.Scan(); number++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if pending == "" {
+			pendingLine = number
+		}
+		if strings.HasSuffix(strings.TrimSpace(line), "\\") {
+			pending += strings.TrimSuffix(strings.TrimSpace(line), "\\") + " "
+			continue
+		}
+		line = strings.TrimSpace(pending + line)
+		pending = ""
+		if line != "" {
+			lines = append(lines, line)
+			numbers = append(numbers, pendingLine)
+		}
+	}
+	return lines, numbers, scanner.Err()
+}
+
+// Imports the first model of a combinational BLIF file
+func ImportBLIF(r io.Reader) (*Netlist, error) {
+	lines, numbers, err := blifLines(r)
+	if err != nil {
+		return nil, err
+	}
+	netlist := newNetlist()
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		switch fields[0] {
+		case ".model":
+			if len(fields) > 1 {
+				netlist.Name = fields[1]
+			}
+		case ".inputs":
+			netlist.Inputs = append(netlist.Inputs, fields[1:]...)
+		case ".outputs":
+			netlist.Outputs = append(netlist.Outputs, fields[1:]...)
+		case ".names":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: .names needs an output", numbers[i])
+			}
+			inputs, output := fields[1:len(fields)-1], fields[len(fields)-1]
+			var cubes []string
+			onSet := true
+			for i+1 < len(lines) && !strings.HasPrefix(lines[i+1], ".") {
+				i++
+				cover := strings.Fields(lines[i])
+				cube, phase := "", cover[len(cover)-1]
+				if len(cover) == 2 {
+					cube = cover[0]
+				}
+				if len(cover) > 2 || len(cube) != len(inputs) || (phase != "0" && phase != "1") {
+					return nil, fmt.Errorf("line %d: invalid cover %q for %d inputs", numbers[i], lines[i], len(inputs))
+				}
+				if len(cubes) > 0 && onSet != (phase == "1") {
+					return nil, fmt.Errorf("line %d: cover mixes on-set and off-set rows", numbers[i])
+				}
+				onSet = phase == "1"
+				cubes = append(cubes, cube)
+			}
+			if err := netlist.names(inputs, output, cubes, onSet); err != nil {
+				return nil, fmt.Errorf("line %d: %w", numbers[i], err)
+			}
+		case ".end":
+			return netlist.finish(), nil
+		default:
+			return nil, fmt.Errorf("line %d: unsupported BLIF construct %s", numbers[i], fields[0])
+		}
+	}
+	return netlist.finish(), nil
+}
+
+type verilogToken struct {
+	text string
+	line int
+}
+
+// Splits structural Verilog into identifiers and punctuation along with the
+// line they are on, dropping comments
+func verilogTokens(r io.Reader) ([]verilogToken, error) {
+	source, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []verilogToken
+	text := string(source)
+	line := 1
+	for i := 0; i < len(text); {
+		c := rune(text[i])
+		switch {
+		case strings.HasPrefix(text[i:], "//"):
+			end := strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = len(text) - i
+			}
+			i += end
+		case strings.HasPrefix(text[i:], "/*"):
+			end := strings.Index(text[i:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(text[i:i+end], "\n")
+			i += end + 2
+		case unicode.IsSpace(c):
+			if c == '\n' {
+				line++
+			}
+			i++
+		case c == '_' || c == '\\' || unicode.IsLetter(c) || unicode.IsDigit(c):
+			start := i
+			for i < len(text) && (text[i] == '_' || text[i] == '$' || text[i] == '\\' ||
+				unicode.IsLetter(rune(text[i])) || unicode.IsDigit(rune(text[i]))) {
+				i++
+			}
+			tokens = append(tokens, verilogToken{text[start:i], line})
+		default:
+			tokens = append(tokens, verilogToken{string(c), line})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+var verilogGates = map[string]bool{
+	"and": true, "or": true, "nand": true, "nor": true,
+	"xor": true, "xnor": true, "not": true, "buf": true,
+}
+
+// Imports the first module of a structural Verilog file built out of gate
+// primitives
+func ImportStructuralVerilog(r io.Reader) (*Netlist, error) {
+	tokens, err := verilogTokens(r)
+	if err != nil {
+		return nil, err
+	}
+	netlist := newNetlist()
+	position := 0
+	// line of the last token read
+	line := 1
+	next := func() string {
+		if position >= len(tokens) {
+			return ""
+		}
+		position++
+		line = tokens[position-1].line
+		return tokens[position-1].text
+	}
+	fail := func(format string, args ...any) error {
+		return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+	}
+	expect := func(token string) error {
+		if got := next(); got != token {
+			return fail("expected %q but got %q", token, got)
+		}
+		return nil
+	}
+	// reads a comma separated list of names up to end. Nets are single bits,
+	// vector ranges and bit selects are rejected
+	names := func(end string) ([]string, error) {
+		var list []string
+		for {
+			name := next()
+			switch name {
+			case end:
+				return list, nil
+			case ",":
+				continue
+			case "":
+				return nil, fail("unexpected end of file")
+			case "[":
+				return nil, fail("unsupported vector range or bit select, only single bit nets are supported")
+			}
+			list = append(list, name)
+		}
+	}
+	declare := func(direction string, list []string) {
+		switch direction {
+		case "input":
+			netlist.Inputs = append(netlist.Inputs, list...)
+		case "output":
+			netlist.Outputs = append(netlist.Outputs, list...)
+		}
+	}
+
+	if err := expect("module"); err != nil {
+		return nil, err
+	}
+	netlist.Name = next()
+	if err := expect("("); err != nil {
+		return nil, err
+	}
+	ports, err := names(")")
+	if err != nil {
+		return nil, err
+	}
+	// ANSI style headers declare directions inside the port list
+	direction := ""
+	for _, port := range ports {
+		switch port {
+		case "input", "output":
+			direction = port
+		case "wire":
+		default:
+			declare(direction, []string{port})
+		}
+	}
+	if err := expect(";"); err != nil {
+		return nil, err
+	}
+
+	for {
+		token := next()
+		switch {
+		case token == "endmodule" || token == "":
+			return netlist.finish(), nil
+		case token == "input" || token == "output" || token == "wire":
+			list, err := names(";")
+			if err != nil {
+				return nil, err
+			}
+			declare(token, list)
+		case token == "assign":
+			list, err := names(";")
+			if err != nil {
+				return nil, err
+			}
+			if len(list) != 3 || list[1] != "=" {
+				return nil, fail("only assignments between nets are supported")
+			}
+			netlist.drive(list[0], netlist.net(list[2]))
+		case verilogGates[token]:
+			instance := next()
+			if instance != "(" {
+				if err := expect("("); err != nil {
+					return nil, err
+				}
+			}
+			terminals, err := names(")")
+			if err != nil {
+				return nil, err
+			}
+			if err := expect(";"); err != nil {
+				return nil, err
+			}
+			if len(terminals) < 2 {
+				return nil, fail("gate %s needs an output and inputs", token)
+			}
+			if err := netlist.gate(token, terminals[0], terminals[1:]); err != nil {
+				return nil, fail("%s", err)
+			}
+		default:
+			return nil, fail("unsupported Verilog construct %q", token)
+		}
+	}
+}
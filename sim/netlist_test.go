@@ -0,0 +1,149 @@
+package sim
+
+import (
+	"strings"
+	"testing"
+)
+
+func checkNetlist(t *testing.T, netlist *Netlist, function func(inputs []bool) []bool) {
+	for vector := range 1 << len(netlist.Inputs) {
+		inputs := make([]bool, len(netlist.Inputs))
+		for i, input := range netlist.Inputs {
+			inputs[i] = vector&(1<<i) != 0
+			if err := netlist.SetInput(input, boolToState(inputs[i])); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := netlist.Circuit.Tick(); err != nil {
+			t.Fatalf("inputs %v: %s", inputs, err.Error())
+		}
+		for i, expected := range function(inputs) {
+			state, err := netlist.Output(netlist.Outputs[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state != boolToState(expected) {
+				t.Errorf("inputs %v generated %s for %s instead of %s",
+					inputs, state, netlist.Outputs[i], boolToState(expected))
+			}
+		}
+	}
+}
+
+func TestImportBLIF(t *testing.T) {
+	blif := `
+# full adder with a majority carry and an off-set cover
+.model full_adder
+.inputs a b \
+  cin
+.outputs sum cout nand
+.names a b x
+10 1
+01 1
+.names x cin sum
+10 1
+01 1
+.names a b cin cout
+11- 1
+1-1 1
+-11 1
+.names a b nand
+11 0
+.end
+`
+	netlist, err := ImportBLIF(strings.NewReader(blif))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if netlist.Name != "full_adder" {
+		t.Errorf("expected model full_adder but got %s", netlist.Name)
+	}
+	checkNetlist(t, netlist, func(in []bool) []bool {
+		a, b, cin := in[0], in[1], in[2]
+		return []bool{a != b != cin, a && b || a && cin || b && cin, !(a && b)}
+	})
+}
+
+func TestImportBLIFMapsTwoInputGates(t *testing.T) {
+	netlist, err := ImportBLIF(strings.NewReader(".model m\n.inputs a b\n.outputs y\n.names a b y\n1- 1\n-1 1\n.end\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(netlist.Components) != 1 || netlist.Components[0].(*CustomComponent).ComponentType != "OrGate" {
+		t.Errorf("expected a single OrGate but got %d components", len(netlist.Components))
+	}
+}
+
+func TestImportBLIFConstants(t *testing.T) {
+	blif := ".model constants\n.inputs a\n.outputs one zero y\n.names one\n1\n.names zero\n.names a one y\n11 1\n.end\n"
+	for range 2 {
+		netlist, err := ImportBLIF(strings.NewReader(blif))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkNetlist(t, netlist, func(in []bool) []bool {
+			return []bool{true, false, in[0]}
+		})
+		for _, output := range netlist.Outputs {
+			net := ConnectedNodes(netlist.Nets[output])
+			if net[SharedSourceNode] || net[SharedGroundNode] {
+				t.Errorf("expected %s to be driven by the netlist instead of the shared supply", output)
+			}
+		}
+	}
+}
+
+func TestImportStructuralVerilog(t *testing.T) {
+	verilog := `
+// half adder plus a few extra gates
+module half_adder (a, b, s, c, n, o);
+  input a, b;
+  output s, c, n, o;
+  wire w;
+  /* gates */
+  xor g1 (s, a, b);
+  and (c, a, b);
+  nor g3 (w, a, b);
+  not g4 (n, w);
+  assign o = n;
+endmodule
+`
+	netlist, err := ImportStructuralVerilog(strings.NewReader(verilog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNetlist(t, netlist, func(in []bool) []bool {
+		a, b := in[0], in[1]
+		return []bool{a != b, a && b, a || b, a || b}
+	})
+}
+
+func TestImportStructuralVerilogANSIPorts(t *testing.T) {
+	verilog := "module m (input a, input b, output y);\n  nand (y, a, b);\nendmodule\n"
+	netlist, err := ImportStructuralVerilog(strings.NewReader(verilog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNetlist(t, netlist, func(in []bool) []bool {
+		return []bool{!(in[0] && in[1])}
+	})
+}
+
+func TestImportStructuralVerilogRejectsVectors(t *testing.T) {
+	tt := []struct {
+		verilog string
+		line    string
+	}{
+		{"module m (a, y);\n  input [3:0] a;\n  output y;\n  not (y, a);\nendmodule\n", "line 2:"},
+		{"module m (a, y);\n  input a;\n  output y;\n  not (y, a[0]);\nendmodule\n", "line 4:"},
+		{"module m (input [1:0] a, output y);\nendmodule\n", "line 1:"},
+	}
+	for _, tc := range tt {
+		_, err := ImportStructuralVerilog(strings.NewReader(tc.verilog))
+		if err == nil {
+			t.Errorf("expected vectors to be rejected in\n%s", tc.verilog)
+		} else if !strings.HasPrefix(err.Error(), tc.line) {
+			t.Errorf("expected the error to start with %q but got %q", tc.line, err.Error())
+		}
+	}
+}
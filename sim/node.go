@@ -0,0 +1,182 @@
+package sim
+
+import "fmt"
+
+type NodeState int
+
+const (
+	Off = iota
+	On
+	Undefined
+)
+
+func (n NodeState) String() string {
+	switch n {
+	case Off:
+		return "off"
+	case On:
+		return "on"
+	case Undefined:
+		return "undefined"
+	default:
+		return "unknown"
+	}
+}
+
+type Node struct {
+	ID          string
+	State       NodeState
+	connections []*Node
+	Parent      Component
+	// Whether the node is held at State by Force
+	forced bool
+	// State of the node at the end of the previous step, Off on power on.
+	// Feedback loops are seeded with it
+	held NodeState
+
+	// Offset relative to component resource
+	OffsetX float32
+	OffsetY float32
+}
+
+// TODO: determine ID automatically
+func NewNode(id string) *Node {
+	return &Node{
+		ID:          id,
+		State:       Undefined,
+		connections: []*Node{},
+	}
+}
+
+// Error of a node driven to a state other than the one it holds
+type ConflictError struct {
+	Node     *Node
+	From, To NodeState
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflicting values for node %s", e.Node.ID)
+}
+
+// Changes the state of n and of the nodes connected to it, failing if n
+// already holds a different defined state. Forced nodes keep their state
+func (n *Node) Change(newState NodeState) error {
+	if n.forced {
+		return nil
+	}
+	if n.State != Undefined && n.State != newState {
+		return &ConflictError{Node: n, From: n.State, To: newState}
+	}
+	n.State = newState
+	for _, node := range n.connections {
+		if node.State == newState {
+			continue
+		}
+		node.Change(newState)
+	}
+	return nil
+}
+
+// Sets n back to Undefined, unless it is forced
+func (n *Node) Reset() {
+	if !n.forced {
+		n.State = Undefined
+	}
+}
+
+// Holds every node connected to n at state, ignoring the changes and resets
+// of the circuit until Release
+func (n *Node) Force(state NodeState) {
+	n.force(true, state, map[*Node]bool{})
+}
+
+func (n *Node) Release() {
+	n.force(false, n.State, map[*Node]bool{})
+}
+
+func (n *Node) Forced() bool {
+	return n.forced
+}
+
+func (n *Node) force(forced bool, state NodeState, visited map[*Node]bool) {
+	if visited[n] {
+		return
+	}
+	visited[n] = true
+	n.forced = forced
+	n.State = state
+	for _, node := range n.connections {
+		node.force(forced, state, visited)
+	}
+}
+
+func (n *Node) Debug() string {
+	return fmt.Sprintf("%s=<state: %s> (offX: %f, offY: %f)", n.ID, n.State, n.OffsetX, n.OffsetY)
+}
+
+// Copy of n owned by parent, with no connections
+func (n *Node) clone(parent Component) *Node {
+	copied := *n
+	copied.connections = []*Node{}
+	copied.Parent = parent
+	return &copied
+}
+
+// Nodes directly connected to n
+func (n *Node) Connections() []*Node {
+	return n.connections
+}
+
+func (n *Node) Connect(n1 *Node) *Node {
+	if n1 != nil {
+		n.connections = append(n.connections, n1)
+		n1.connections = append(n1.connections, n)
+	}
+	return n
+}
+
+func removeFromList(l []*Node, e *Node) []*Node {
+	for i, el := range l {
+		if el == e {
+			l[i] = l[len(l)-1]
+			return l[:len(l)-1]
+		}
+	}
+	return l
+}
+
+// TODO: update node data structure to favor disconnections
+func (n *Node) Disconnect(n1 *Node) *Node {
+	if n1 != nil {
+		n.connections = removeFromList(n.connections, n1)
+		n1.connections = removeFromList(n1.connections, n)
+	}
+	return n
+}
+
+func (n *Node) DisconnectAll() *Node {
+	for _, n1 := range n.connections {
+		// also clears 'n' connections
+		n1.Disconnect(n)
+	}
+	return n
+}
+
+// Returns every node electrically connected to n, including n itself
+func ConnectedNodes(n *Node) map[*Node]bool {
+	net := map[*Node]bool{}
+	pending := []*Node{n}
+	for len(pending) > 0 {
+		node := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if net[node] {
+			continue
+		}
+		net[node] = true
+		pending = append(pending, node.connections...)
+	}
+	return net
+}
+
+var SharedSourceNode = NewNode("SharedSource")
+var SharedGroundNode = NewNode("SharedGround")
@@ -0,0 +1,39 @@
+package sim
+
+// Orientation of a placed component, mirrored first and then rotated
+type Orientation struct {
+	// Quarter turns clockwise, from 0 to 3
+	Rotation int
+	// Mirrored along its vertical axis
+	Flipped bool
+}
+
+// Rotation in degrees clockwise
+func (o Orientation) Degrees() float32 {
+	return float32(o.Rotation * 90)
+}
+
+// Rotates the component a quarter turn clockwise around its center, along
+// with the offsets of its nodes
+func Rotate(c Movable) {
+	for _, node := range c.Nodes() {
+		node.OffsetX, node.OffsetY = 1-node.OffsetY, node.OffsetX
+	}
+	o := c.GetID().Orientation
+	o.Rotation = (o.Rotation + 1) % 4
+	c.SetOrientation(o)
+}
+
+// Mirrors the component along its vertical axis as it is currently drawn,
+// along with the offsets of its nodes
+func Flip(c Movable) {
+	for _, node := range c.Nodes() {
+		node.OffsetX = 1 - node.OffsetX
+	}
+	// mirroring a rotated component is the same as mirroring it first and
+	// rotating it the other way
+	o := c.GetID().Orientation
+	o.Rotation = (4 - o.Rotation) % 4
+	o.Flipped = !o.Flipped
+	c.SetOrientation(o)
+}
@@ -0,0 +1,41 @@
+package sim
+
+import "testing"
+
+func TestRotateAndFlip(t *testing.T) {
+	tt := []struct {
+		operations          string
+		expectedOrientation Orientation
+		// offsets of the first node, starting at (0, 0.5)
+		expectedX, expectedY float32
+	}{
+		{"", Orientation{}, 0, 0.5},
+		{"r", Orientation{Rotation: 1}, 0.5, 0},
+		{"rr", Orientation{Rotation: 2}, 1, 0.5},
+		{"rrrr", Orientation{}, 0, 0.5},
+		{"f", Orientation{Flipped: true}, 1, 0.5},
+		{"ff", Orientation{}, 0, 0.5},
+		{"rf", Orientation{Rotation: 3, Flipped: true}, 0.5, 0},
+		{"fr", Orientation{Rotation: 1, Flipped: true}, 0.5, 1},
+	}
+	for _, tc := range tt {
+		resistor := NewDrawableResistor("R", &Node{OffsetX: 0, OffsetY: 0.5}, &Node{OffsetX: 1, OffsetY: 0.5})
+		for _, operation := range tc.operations {
+			if operation == 'r' {
+				Rotate(resistor)
+			} else {
+				Flip(resistor)
+			}
+		}
+		if resistor.Orientation != tc.expectedOrientation {
+			t.Errorf("%q: expected orientation %+v but got %+v", tc.operations, tc.expectedOrientation, resistor.Orientation)
+		}
+		if resistor.Node1.OffsetX != tc.expectedX || resistor.Node1.OffsetY != tc.expectedY {
+			t.Errorf("%q: expected node at (%v, %v) but got (%v, %v)", tc.operations,
+				tc.expectedX, tc.expectedY, resistor.Node1.OffsetX, resistor.Node1.OffsetY)
+		}
+		if resistor.Node1.OffsetX+resistor.Node2.OffsetX != 1 || resistor.Node1.OffsetY+resistor.Node2.OffsetY != 1 {
+			t.Errorf("%q: nodes are no longer opposite to each other", tc.operations)
+		}
+	}
+}
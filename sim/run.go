@@ -0,0 +1,122 @@
+package sim
+
+// Step of a circuit being run, handed down to the schedulers so that the
+// components acting in it report to the tracer of the circuit
+type run struct {
+	tracer *Tracer
+	step   uint64
+	// nodes of the circuit and their states when last traced, only kept
+	// when tracing node changes
+	nodes  []*Node
+	traced []NodeState
+	// states feedback loops were seeded with, by node of the seeded nets
+	seeds map[*Node]NodeState
+	// whether a seed differs from the state its driver settled on
+	unsettled bool
+}
+
+func newRun(tracer *Tracer, step uint64, nodes func() []*Node) *run {
+	r := &run{tracer: tracer, step: step, seeds: map[*Node]NodeState{}}
+	if r.enabled(TraceVerbose) {
+		r.nodes = nodes()
+		for _, node := range r.nodes {
+			r.traced = append(r.traced, node.State)
+		}
+	}
+	return r
+}
+
+// Seeds the undefined inputs of the first of the deferred components driven
+// by another one with the states their nets held on the previous step, so
+// that the feedback loop deferring them can act. Inputs nothing drives stay
+// undefined. Returns whether any net was seeded
+func (r *run) seed(deferred []Component) bool {
+	if r == nil {
+		return false
+	}
+	driven := map[*Node]bool{}
+	for _, component := range deferred {
+		_, outputs := Ports(component)
+		for _, output := range outputs {
+			driven[output] = true
+		}
+	}
+	for _, component := range deferred {
+		inputs, outputs := Ports(component)
+		if len(outputs) == 0 {
+			continue
+		}
+		seeded := false
+		for _, input := range inputs {
+			if input.State != Undefined || !isDriven(input, driven) {
+				continue
+			}
+			state := input.held
+			if err := input.Change(state); err != nil {
+				continue
+			}
+			for node := range ConnectedNodes(input) {
+				r.seeds[node] = state
+			}
+			seeded = true
+		}
+		if seeded {
+			r.traceChanges()
+			return true
+		}
+	}
+	return false
+}
+
+func isDriven(input *Node, driven map[*Node]bool) bool {
+	for node := range ConnectedNodes(input) {
+		if driven[node] {
+			return true
+		}
+	}
+	return false
+}
+
+// Clears the seeded nets among the outputs of component so that it drives
+// them, returning the seeds to check once it acted
+func (r *run) release(component Component) map[*Node]NodeState {
+	if r == nil || len(r.seeds) == 0 {
+		return nil
+	}
+	_, outputs := Ports(component)
+	released := map[*Node]NodeState{}
+	for _, output := range outputs {
+		state, ok := r.seeds[output]
+		if !ok {
+			continue
+		}
+		released[output] = state
+		for node := range ConnectedNodes(output) {
+			delete(r.seeds, node)
+			if !node.forced {
+				node.State = Undefined
+			}
+		}
+	}
+	return released
+}
+
+// Compares the states driven on the released nets with their seeds. Nets
+// left undefined keep their seed
+func (r *run) check(released map[*Node]NodeState) error {
+	for output, state := range released {
+		switch output.State {
+		case state:
+		case Undefined:
+			if err := output.Change(state); err != nil {
+				return err
+			}
+			for node := range ConnectedNodes(output) {
+				r.seeds[node] = state
+			}
+		default:
+			r.unsettled = true
+		}
+	}
+	return nil
+}
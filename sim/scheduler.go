@@ -0,0 +1,169 @@
+package sim
+
+// Component acting components of its own, which act in the step it acts in
+type composite interface {
+	actIn(r *run) error
+}
+
+func act(c Component, r *run) error {
+	if composite, ok := c.(composite); ok {
+		return composite.actIn(r)
+	}
+	return c.Act()
+}
+
+type schedulerPhase int
+
+const (
+	phaseTransistors schedulerPhase = iota
+	phaseOthers
+	phaseResistors
+)
+
+// Order components act in. Every round goes over the transistors until they
+// stop making progress, then over the other components and the resistors
+// once, and the components deferred by a round because their inputs were not
+// ready are considered again by the next one. A round where nothing acted
+// seeds the feedback loop deferring its components when running in a step
+type Scheduler struct {
+	maxDefers int
+	round     int
+	phase     schedulerPhase
+	// components of the round, split by kind
+	transistors, others, resistors []Component
+	// components of the current pass and the position of the next one
+	pass []Component
+	next int
+	// components deferred by the current pass and by the finished passes of
+	// the round
+	deferred      []Component
+	roundDeferred []Component
+	// whether any component acted in the current round
+	progress bool
+	done     bool
+	// step the components act in, nil when not traced
+	run *run
+}
+
+func NewScheduler(components []Component, maxDefers int) *Scheduler {
+	return newScheduler(components, maxDefers, nil)
+}
+
+func newScheduler(components []Component, maxDefers int, r *run) *Scheduler {
+	s := &Scheduler{maxDefers: maxDefers, run: r}
+	s.startRound(components)
+	return s
+}
+
+func (s *Scheduler) startRound(components []Component) {
+	if s.round >= s.maxDefers {
+		s.done = true
+		return
+	}
+	s.transistors, s.resistors, s.others = SplitComponents(components)
+	s.roundDeferred = nil
+	s.progress = false
+	s.startPass(phaseTransistors, s.transistors)
+}
+
+func (s *Scheduler) startPass(phase schedulerPhase, components []Component) {
+	s.phase = phase
+	s.pass = components
+	s.next = 0
+	s.deferred = nil
+}
+
+func (s *Scheduler) endPass() {
+	switch s.phase {
+	case phaseTransistors:
+		// transistors can enable each other, go over them again while any
+		// of them acted
+		if len(s.deferred) < len(s.pass) {
+			s.startPass(phaseTransistors, s.deferred)
+			return
+		}
+		s.roundDeferred = append(s.roundDeferred, s.deferred...)
+		s.startPass(phaseOthers, s.others)
+	case phaseOthers:
+		s.roundDeferred = append(s.roundDeferred, s.deferred...)
+		s.startPass(phaseResistors, s.resistors)
+	case phaseResistors:
+		s.roundDeferred = append(s.roundDeferred, s.deferred...)
+		if len(s.roundDeferred) == 0 {
+			s.round++
+			s.done = true
+			return
+		}
+		// a round where nothing acted is stuck on a feedback loop, seeding
+		// the loop does not use up a round
+		if s.progress || !s.run.seed(s.roundDeferred) {
+			s.round++
+		}
+		s.startRound(s.roundDeferred)
+	}
+}
+
+// Moves past finished passes so that the next component is at hand
+func (s *Scheduler) settle() {
+	for !s.done && s.next == len(s.pass) {
+		s.endPass()
+	}
+}
+
+// Whether every component acted or the rounds ran out
+func (s *Scheduler) Done() bool {
+	s.settle()
+	return s.done
+}
+
+// Considers the next component, acting it when it is ready and deferring it
+// otherwise
+func (s *Scheduler) Next() (component Component, acted bool, err error) {
+	s.settle()
+	if s.done {
+		return nil, false, nil
+	}
+	component = s.pass[s.next]
+	s.next++
+	debug := s.run.enabled(TraceDebug)
+	if !component.Ready() {
+		if debug {
+			s.run.trace(TraceDebug, TraceEvent{Kind: TraceComponentDeferred, Component: ComponentName(component)})
+		}
+		s.deferred = append(s.deferred, component)
+		return component, false, nil
+	}
+	released := s.run.release(component)
+	err = act(component, s.run)
+	if err == nil {
+		err = s.run.check(released)
+	}
+	s.run.traceChanges()
+	if err != nil {
+		return component, false, err
+	}
+	s.progress = true
+	if debug {
+		s.run.trace(TraceDebug, TraceEvent{Kind: TraceComponentActed, Component: ComponentName(component)})
+	}
+	return component, true, nil
+}
+
+// Components left to consider in the current pass, in order
+func (s *Scheduler) Pending() []Component {
+	s.settle()
+	if s.done {
+		return nil
+	}
+	return s.pass[s.next:]
+}
+
+// Components deferred to a later pass or round so far
+func (s *Scheduler) Deferred() []Component {
+	return append(append([]Component{}, s.roundDeferred...), s.deferred...)
+}
+
+// Rounds started so far
+func (s *Scheduler) Round() int {
+	return s.round
+}
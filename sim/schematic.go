@@ -0,0 +1,385 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// Version written to new schematic files, bump when the format changes in a
+// way older loaders cannot read
+const SchematicVersion = 1
+
+type Schematic struct {
+	Version     int                   `json:"version"`
+	Components  []SchematicComponent  `json:"components"`
+	Connections []SchematicConnection `json:"connections"`
+}
+
+type SchematicComponent struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	ID   string `json:"id"`
+	X    int32  `json:"x"`
+	Y    int32  `json:"y"`
+	// Quarter turns clockwise and mirroring, node offsets are saved already
+	// oriented
+	Rotation int             `json:"rotation,omitempty"`
+	Flipped  bool            `json:"flipped,omitempty"`
+	Nodes    []SchematicNode `json:"nodes"`
+	// Initial state of terminals
+	State string `json:"state,omitempty"`
+	// Block name and abstraction level of block instances
+	Block string `json:"block,omitempty"`
+	Level string `json:"level,omitempty"`
+}
+
+type SchematicNode struct {
+	ID      string  `json:"id"`
+	OffsetX float32 `json:"offsetX"`
+	OffsetY float32 `json:"offsetY"`
+}
+
+// Reference to the node at index Node of Component.Nodes()
+type SchematicNodeRef struct {
+	Component string `json:"component"`
+	Node      int    `json:"node"`
+}
+
+type SchematicConnection struct {
+	From SchematicNodeRef `json:"from"`
+	To   SchematicNodeRef `json:"to"`
+	// Points the wire drawn from From to To passes through
+	Waypoints []Position `json:"waypoints,omitempty"`
+}
+
+func schematicType(c Component) (string, error) {
+	switch c := c.(type) {
+	case *Terminal:
+		return c.terminalType, nil
+	case *Meter:
+		return "Multimeter", nil
+	case *Resistor:
+		return "Resistor", nil
+	case *Transistor:
+		return "Transistor", nil
+	case *BlockInstance:
+		return "Block", nil
+	}
+	return "", fmt.Errorf("component %s cannot be saved to a schematic", c.Debug())
+}
+
+func parseNodeState(state string) (NodeState, error) {
+	switch state {
+	case "off":
+		return Off, nil
+	case "on":
+		return On, nil
+	case "undefined":
+		return Undefined, nil
+	}
+	return Undefined, fmt.Errorf("unknown node state %q", state)
+}
+
+func parseAbstractionLevel(level string) (AbstractionLevel, error) {
+	switch level {
+	case "transistor":
+		return LevelTransistor, nil
+	case "behavioral":
+		return LevelBehavioral, nil
+	}
+	return LevelTransistor, fmt.Errorf("unknown abstraction level %q", level)
+}
+
+// Nodes reachable from n through wires and free nodes, stopping at nodes
+// owned by a component. Connections through free nodes are saved as direct
+// connections between the component nodes they join
+func connectedComponentNodes(n *Node) []*Node {
+	visited := map[*Node]bool{n: true}
+	pending := append([]*Node{}, n.connections...)
+	var reached []*Node
+	for len(pending) > 0 {
+		node := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if visited[node] {
+			continue
+		}
+		visited[node] = true
+		if node.Parent != nil {
+			reached = append(reached, node)
+			continue
+		}
+		pending = append(pending, node.connections...)
+	}
+	return reached
+}
+
+// Describes the components and their connections, saving the waypoints of the
+// wires drawn for them
+func NewSchematic(components []Component, wires ...*Wire) (*Schematic, error) {
+	schematic := &Schematic{Version: SchematicVersion}
+	refs := map[*Node]SchematicNodeRef{}
+	for i, component := range components {
+		componentType, err := schematicType(component)
+		if err != nil {
+			return nil, err
+		}
+		id := component.GetID()
+		if id.ID == "" {
+			// components built outside the editor have no ID
+			id.ID = fmt.Sprintf("auto-%d", i)
+		}
+		entry := SchematicComponent{
+			Type:     componentType,
+			Name:     id.Name,
+			ID:       id.ID,
+			X:        id.X,
+			Y:        id.Y,
+			Rotation: id.Rotation,
+			Flipped:  id.Flipped,
+		}
+		for i, node := range component.Nodes() {
+			entry.Nodes = append(entry.Nodes, SchematicNode{ID: node.ID, OffsetX: node.OffsetX, OffsetY: node.OffsetY})
+			refs[node] = SchematicNodeRef{Component: id.ID, Node: i}
+		}
+		switch component := component.(type) {
+		case *Terminal:
+			entry.State = component.state.String()
+		case *BlockInstance:
+			entry.Block = component.Block.Name
+			entry.Level = component.Level.String()
+		}
+		schematic.Components = append(schematic.Components, entry)
+	}
+
+	waypoints := map[[2]*Node][]Position{}
+	for _, wire := range wires {
+		reversed := slices.Clone(wire.Waypoints)
+		slices.Reverse(reversed)
+		waypoints[[2]*Node{wire.From, wire.To}] = wire.Waypoints
+		waypoints[[2]*Node{wire.To, wire.From}] = reversed
+	}
+	saved := map[[2]*Node]bool{}
+	for _, component := range components {
+		for _, node := range component.Nodes() {
+			for _, other := range connectedComponentNodes(node) {
+				otherRef, ok := refs[other]
+				if !ok || saved[[2]*Node{other, node}] {
+					continue
+				}
+				saved[[2]*Node{node, other}] = true
+				schematic.Connections = append(schematic.Connections, SchematicConnection{
+					From:      refs[node],
+					To:        otherRef,
+					Waypoints: waypoints[[2]*Node{node, other}],
+				})
+			}
+		}
+	}
+	return schematic, nil
+}
+
+func schematicNodes(entry SchematicComponent, count int) ([]*Node, error) {
+	if len(entry.Nodes) != count {
+		return nil, fmt.Errorf("%s %s has %d nodes instead of %d", entry.Type, entry.ID, len(entry.Nodes), count)
+	}
+	nodes := make([]*Node, count)
+	for i, n := range entry.Nodes {
+		nodes[i] = NewNode(n.ID)
+		nodes[i].OffsetX = n.OffsetX
+		nodes[i].OffsetY = n.OffsetY
+	}
+	return nodes, nil
+}
+
+// Builds the component described by entry
+func (entry SchematicComponent) build() (Component, error) {
+	var component Component
+	switch entry.Type {
+	case "Source", "Ground", "Input", "Clock":
+		nodes, err := schematicNodes(entry, 1)
+		if err != nil {
+			return nil, err
+		}
+		state, err := parseNodeState(entry.State)
+		if err != nil {
+			return nil, err
+		}
+		component = &Terminal{Node: nodes[0], state: state, terminalType: entry.Type}
+	case "Multimeter":
+		nodes, err := schematicNodes(entry, 1)
+		if err != nil {
+			return nil, err
+		}
+		component = &Meter{Node: nodes[0]}
+	case "Resistor":
+		nodes, err := schematicNodes(entry, 2)
+		if err != nil {
+			return nil, err
+		}
+		component = &Resistor{Node1: nodes[0], Node2: nodes[1]}
+	case "Transistor":
+		nodes, err := schematicNodes(entry, 3)
+		if err != nil {
+			return nil, err
+		}
+		component = &Transistor{Source: nodes[0], Gate: nodes[1], Drain: nodes[2]}
+	case "Block":
+		block, ok := FindBlock(entry.Block)
+		if !ok {
+			return nil, fmt.Errorf("unknown block %q", entry.Block)
+		}
+		level, err := parseAbstractionLevel(entry.Level)
+		if err != nil {
+			return nil, err
+		}
+		instance := NewBlockInstance(entry.Name, block, nil, level)
+		if len(entry.Nodes) != len(instance.Nodes()) {
+			return nil, fmt.Errorf("block %s has %d nodes instead of %d", entry.ID, len(entry.Nodes), len(instance.Nodes()))
+		}
+		for i, node := range instance.Nodes() {
+			node.OffsetX = entry.Nodes[i].OffsetX
+			node.OffsetY = entry.Nodes[i].OffsetY
+		}
+		component = instance
+	default:
+		return nil, fmt.Errorf("unknown component type %q", entry.Type)
+	}
+
+	if entry.Rotation < 0 || entry.Rotation > 3 {
+		return nil, fmt.Errorf("%s %s has rotation %d, expected 0 to 3 quarter turns", entry.Type, entry.ID, entry.Rotation)
+	}
+	id := ComponentID{
+		Name:        entry.Name,
+		ID:          entry.ID,
+		Position:    Position{entry.X, entry.Y},
+		Orientation: Orientation{Rotation: entry.Rotation, Flipped: entry.Flipped},
+	}
+	switch c := component.(type) {
+	case *Terminal:
+		c.ComponentID = id
+	case *Meter:
+		c.ComponentID = id
+	case *Resistor:
+		c.ComponentID = id
+	case *Transistor:
+		c.ComponentID = id
+	case *BlockInstance:
+		c.ComponentID = id
+	}
+	for _, node := range component.Nodes() {
+		node.Parent = component
+	}
+	return component, nil
+}
+
+// Rebuilds the components described by the schematic and their connections
+func (s *Schematic) Build() ([]Component, error) {
+	components, _, err := s.BuildWires()
+	return components, err
+}
+
+// Rebuilds the components along with a wire for every connection
+func (s *Schematic) BuildWires() ([]Component, []*Wire, error) {
+	if s.Version < 1 || s.Version > SchematicVersion {
+		return nil, nil, fmt.Errorf("unsupported schematic version %d", s.Version)
+	}
+	components := make([]Component, 0, len(s.Components))
+	byID := map[string]Component{}
+	for _, entry := range s.Components {
+		if _, ok := byID[entry.ID]; ok {
+			return nil, nil, fmt.Errorf("duplicate component id %q", entry.ID)
+		}
+		component, err := entry.build()
+		if err != nil {
+			return nil, nil, err
+		}
+		byID[entry.ID] = component
+		components = append(components, component)
+	}
+
+	resolve := func(ref SchematicNodeRef) (*Node, error) {
+		component, ok := byID[ref.Component]
+		if !ok {
+			return nil, fmt.Errorf("connection references unknown component %q", ref.Component)
+		}
+		nodes := component.Nodes()
+		if ref.Node < 0 || ref.Node >= len(nodes) {
+			return nil, fmt.Errorf("connection references node %d of %q which has %d nodes", ref.Node, ref.Component, len(nodes))
+		}
+		return nodes[ref.Node], nil
+	}
+	var wires []*Wire
+	for _, connection := range s.Connections {
+		from, err := resolve(connection.From)
+		if err != nil {
+			return nil, nil, err
+		}
+		to, err := resolve(connection.To)
+		if err != nil {
+			return nil, nil, err
+		}
+		wire := NewWire(from, to, connection.Waypoints...)
+		wire.Connect()
+		wires = append(wires, wire)
+	}
+	return components, wires, nil
+}
+
+// Largest numeric component ID in the schematic, so new IDs do not collide
+func (s *Schematic) MaxID() int {
+	maxID := -1
+	for _, entry := range s.Components {
+		if id, err := strconv.Atoi(entry.ID); err == nil && id > maxID {
+			maxID = id
+		}
+	}
+	return maxID
+}
+
+func WriteSchematic(w io.Writer, components []Component, wires ...*Wire) error {
+	schematic, err := NewSchematic(components, wires...)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(schematic)
+}
+
+func ReadSchematic(r io.Reader) (*Schematic, error) {
+	var schematic Schematic
+	if err := json.NewDecoder(r).Decode(&schematic); err != nil {
+		return nil, fmt.Errorf("invalid schematic: %w", err)
+	}
+	return &schematic, nil
+}
+
+func SaveSchematic(path string, components []Component, wires ...*Wire) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return WriteSchematic(f, components, wires...)
+}
+
+func LoadSchematic(path string) ([]Component, error) {
+	schematic, err := OpenSchematic(path)
+	if err != nil {
+		return nil, err
+	}
+	return schematic.Build()
+}
+
+func OpenSchematic(path string) (*Schematic, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSchematic(f)
+}
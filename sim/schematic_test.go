@@ -0,0 +1,122 @@
+package sim
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newTestSchematic() []Component {
+	input := NewInput("Input 1", nil, On)
+	input.ComponentID = ComponentID{Name: "Input 1", ID: "0", Position: Position{200, 100}}
+
+	block, _ := FindBlock("NotGate")
+	not := NewBlockInstance("NotGate 1", block, nil, LevelBehavioral)
+	not.ComponentID = ComponentID{Name: "NotGate 1", ID: "1", Position: Position{400, 100}}
+	Rotate(not)
+
+	meter := NewMultimeter("Multimeter 1", nil)
+	meter.ComponentID = ComponentID{Name: "Multimeter 1", ID: "2", Position: Position{600, 100}}
+
+	input.Node.Connect(not.Inputs[0])
+	not.Outputs[0].Connect(meter.Node)
+	return []Component{input, not, meter}
+}
+
+func TestSchematicRoundTrip(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := WriteSchematic(&buffer, newTestSchematic()); err != nil {
+		t.Fatal(err)
+	}
+	schematic, err := ReadSchematic(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schematic.Version != SchematicVersion {
+		t.Errorf("expected version %d but got %d", SchematicVersion, schematic.Version)
+	}
+	if len(schematic.Connections) != 2 {
+		t.Errorf("expected 2 connections but got %d", len(schematic.Connections))
+	}
+	if schematic.MaxID() != 2 {
+		t.Errorf("expected max id 2 but got %d", schematic.MaxID())
+	}
+
+	components, err := schematic.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(components) != 3 {
+		t.Fatalf("expected 3 components but got %d", len(components))
+	}
+	input, ok := components[0].(*Terminal)
+	if !ok || input.terminalType != "Input" || input.state != On {
+		t.Errorf("expected an Input terminal in state on but got %s", components[0].Debug())
+	}
+	not, ok := components[1].(*BlockInstance)
+	if !ok || not.Block.Name != "NotGate" || not.Level != LevelBehavioral {
+		t.Fatalf("expected a behavioral NotGate block but got %s", components[1].Debug())
+	}
+	if id := not.GetID(); id.Name != "NotGate 1" || id.ID != "1" || id.X != 400 || id.Y != 100 || id.Rotation != 1 {
+		t.Errorf("component id was not restored, got %+v", id)
+	}
+	if not.Inputs[0].OffsetX != 0.5 || not.Inputs[0].OffsetY != 0 {
+		t.Errorf("expected the rotated input at the top but got (%v, %v)", not.Inputs[0].OffsetX, not.Inputs[0].OffsetY)
+	}
+	meter := components[2].(*Meter)
+
+	if err := NewCircuit(components, 10, false).Tick(); err != nil {
+		t.Fatal(err)
+	}
+	if meter.Node.State != Off {
+		t.Errorf("expected loaded circuit to measure off but got %s", meter.Node.State)
+	}
+}
+
+func TestSchematicRejectsUnknownVersion(t *testing.T) {
+	schematic, err := ReadSchematic(strings.NewReader(`{"version": 99, "components": [], "connections": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := schematic.Build(); err == nil {
+		t.Errorf("expected an error when loading an unknown version")
+	}
+}
+
+func TestSchematicWireWaypoints(t *testing.T) {
+	components := newTestSchematic()
+	input, not, meter := components[0].(*Terminal), components[1].(*BlockInstance), components[2].(*Meter)
+	// drawn from the meter back to the gate, saved from the gate to the meter
+	wires := []*Wire{
+		NewWire(input.Node, not.Inputs[0], Position{300, 50}),
+		NewWire(meter.Node, not.Outputs[0], Position{550, 200}, Position{500, 200}),
+	}
+	var buffer bytes.Buffer
+	if err := WriteSchematic(&buffer, components, wires...); err != nil {
+		t.Fatal(err)
+	}
+	schematic, err := ReadSchematic(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, loadedWires, err := schematic.BuildWires()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loadedWires) != 2 {
+		t.Fatalf("expected 2 wires but got %d", len(loadedWires))
+	}
+	expected := [][]Position{{{300, 50}}, {{500, 200}, {550, 200}}}
+	for i, wire := range loadedWires {
+		if !slices.Equal(wire.Waypoints, expected[i]) {
+			t.Errorf("expected wire %d through %v but got %v", i, expected[i], wire.Waypoints)
+		}
+		if !slices.Contains(wire.From.Connections(), wire.To) {
+			t.Errorf("wire %d does not connect its nodes", i)
+		}
+	}
+	if loadedWires[0].From != loaded[0].Nodes()[0] || loadedWires[1].To != loaded[2].Nodes()[0] {
+		t.Errorf("wires were not built between the loaded nodes")
+	}
+}
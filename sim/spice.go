@@ -0,0 +1,206 @@
+package sim
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type SpiceOptions struct {
+	Title string
+	// Name of the MOSFET model used by every transistor
+	ModelName string
+	// Model card emitted verbatim, usually a .model or .include line
+	ModelCard string
+	// Extra instance parameters appended to each MOSFET, e.g. "W=1u L=1u"
+	TransistorParameters string
+	ResistorValue        string
+	SupplyVoltage        float64
+}
+
+var DefaultSpiceOptions = SpiceOptions{
+	Title:         "copooter circuit",
+	ModelName:     "NMOS1",
+	ModelCard:     ".model NMOS1 NMOS (LEVEL=1 VTO=0.7 KP=110u)",
+	ResistorValue: "10k",
+	SupplyVoltage: 5,
+}
+
+// Node names accepted by every SPICE flavour
+func sanitizeNetName(name string) string {
+	var builder strings.Builder
+	for _, r := range name {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			builder.WriteRune(r)
+		} else {
+			builder.WriteRune('_')
+		}
+	}
+	return builder.String()
+}
+
+// Groups nodes into nets and names each net after the IDs of its nodes.
+// Free nodes are preferred since they are the named wires of the builders
+type netNamer struct {
+	names map[*Node]string
+	used  map[string]bool
+}
+
+func newNetNamer() *netNamer {
+	return &netNamer{names: map[*Node]string{}, used: map[string]bool{}}
+}
+
+func (n *netNamer) assign(net map[*Node]bool, name string) {
+	for node := range net {
+		n.names[node] = name
+	}
+}
+
+func (n *netNamer) name(node *Node) string {
+	if name, ok := n.names[node]; ok {
+		return name
+	}
+	net := ConnectedNodes(node)
+	var free, owned []string
+	for member := range net {
+		if member.ID == "" {
+			continue
+		}
+		if member.Parent == nil {
+			free = append(free, member.ID)
+		} else {
+			owned = append(owned, member.ID)
+		}
+	}
+	slices.Sort(free)
+	slices.Sort(owned)
+	base := "net"
+	if len(free) > 0 {
+		base = sanitizeNetName(free[0])
+	} else if len(owned) > 0 {
+		base = sanitizeNetName(owned[0])
+	}
+	name := base
+	for i := 2; n.used[name] || name == "0"; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	n.used[name] = true
+	n.assign(net, name)
+	return name
+}
+
+// Primitive component together with its path in the hierarchy
+type flatComponent struct {
+	path      string
+	component Component
+}
+
+// Expands custom components and block instances down to primitives
+func flattenComponents(components []Component, path string) ([]flatComponent, error) {
+	var flat []flatComponent
+	for _, component := range components {
+		label := ComponentLabel(component)
+		if path != "" {
+			label = path + "/" + label
+		}
+		switch c := component.(type) {
+		case *FunctionComponent:
+			return nil, fmt.Errorf("behavioral component %s has no transistor level description", label)
+		case *BlockInstance:
+			if c.Level != LevelTransistor {
+				return nil, fmt.Errorf("block %s is simulated behaviorally, switch it to transistor level to export it", c.Name)
+			}
+		}
+		if subcomponents := Subcomponents(component); subcomponents != nil {
+			nested, err := flattenComponents(subcomponents, label)
+			if err != nil {
+				return nil, err
+			}
+			flat = append(flat, nested...)
+		} else {
+			flat = append(flat, flatComponent{label, component})
+		}
+	}
+	return flat, nil
+}
+
+// Blocks are powered by the shared supply nodes, whose terminals are exported
+// even when the components leave them out
+func withBaseComponents(components []Component) []Component {
+	var missing []Component
+	for _, base := range BaseComponents {
+		if !slices.Contains(components, base) {
+			missing = append(missing, base)
+		}
+	}
+	return append(missing, components...)
+}
+
+func WriteSpiceNetlist(w io.Writer, components []Component, options SpiceOptions) error {
+	flat, err := flattenComponents(withBaseComponents(components), "")
+	if err != nil {
+		return err
+	}
+
+	nets := newNetNamer()
+	// ground nets are always SPICE node 0
+	for _, f := range flat {
+		if terminal, ok := f.component.(*Terminal); ok && terminal.terminalType == "Ground" {
+			nets.assign(ConnectedNodes(terminal.Node), "0")
+		}
+	}
+
+	var elements, meters []string
+	counts := map[byte]int{}
+	element := func(prefix byte, path, format string, args ...any) {
+		counts[prefix]++
+		elements = append(elements,
+			fmt.Sprintf("* %s", path),
+			fmt.Sprintf("%c%d ", prefix, counts[prefix])+fmt.Sprintf(format, args...),
+		)
+	}
+	for _, f := range flat {
+		switch c := f.component.(type) {
+		case *Transistor:
+			line := fmt.Sprintf("%s %s %s 0 %s", nets.name(c.Drain), nets.name(c.Gate), nets.name(c.Source), options.ModelName)
+			if options.TransistorParameters != "" {
+				line += " " + options.TransistorParameters
+			}
+			element('M', f.path, "%s", line)
+		case *Resistor:
+			element('R', f.path, "%s %s %s", nets.name(c.Node1), nets.name(c.Node2), options.ResistorValue)
+		case *Terminal:
+			switch c.terminalType {
+			case "Ground":
+				continue
+			case "Source":
+				element('V', f.path, "%s 0 DC %g", nets.name(c.Node), options.SupplyVoltage)
+			default:
+				voltage := 0.0
+				if c.state == On {
+					voltage = options.SupplyVoltage
+				}
+				element('V', f.path, "%s 0 DC %g", nets.name(c.Node), voltage)
+			}
+		case *Meter:
+			meters = append(meters, fmt.Sprintf("v(%s)", nets.name(c.Node)))
+		default:
+			return fmt.Errorf("component %s cannot be exported to SPICE", f.path)
+		}
+	}
+
+	lines := []string{options.Title, options.ModelCard, ""}
+	lines = append(lines, elements...)
+	lines = append(lines, "", ".op")
+	if len(meters) > 0 {
+		lines = append(lines, ".save "+strings.Join(meters, " "))
+	}
+	lines = append(lines, ".end")
+	_, err = io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func (c *Circuit) WriteSpiceNetlist(w io.Writer, options SpiceOptions) error {
+	return WriteSpiceNetlist(w, c.Components(), options)
+}
@@ -0,0 +1,71 @@
+package sim
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSpiceNetlistNotGate(t *testing.T) {
+	input := NewNode("A")
+	output, notGate := NewNotGate(input)
+	c := NewCircuit([]Component{
+		NewInput("A", input, On),
+		notGate,
+		NewMultimeter("Y", output),
+	}, 4, false)
+
+	var buffer bytes.Buffer
+	options := DefaultSpiceOptions
+	options.TransistorParameters = "W=2u L=1u"
+	if err := c.WriteSpiceNetlist(&buffer, options); err != nil {
+		t.Fatal(err)
+	}
+	netlist := buffer.String()
+
+	expectedLines := []string{
+		"copooter circuit",
+		options.ModelCard,
+		"V1 SharedSource 0 DC 5",
+		"V2 A 0 DC 5",
+		"R1 SharedSource NotGate_Output 10k",
+		"M1 0 A NotGate_Output 0 NMOS1 W=2u L=1u",
+		".save v(NotGate_Output)",
+		".end",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(netlist, line+"\n") {
+			t.Errorf("expected netlist to contain %q\n%s", line, netlist)
+		}
+	}
+}
+
+func TestSpiceNetlistRejectsBehavioralBlocks(t *testing.T) {
+	block, _ := FindBlock("AndGate")
+	instance := NewBlockInstance("and", block, nil, LevelBehavioral)
+	var buffer bytes.Buffer
+	if err := WriteSpiceNetlist(&buffer, []Component{instance}, DefaultSpiceOptions); err == nil {
+		t.Errorf("expected behavioral blocks to be rejected")
+	}
+	instance.SetLevel(LevelTransistor)
+	if err := WriteSpiceNetlist(&buffer, []Component{instance}, DefaultSpiceOptions); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSpiceNetlistPowersPlacedBlocks(t *testing.T) {
+	input := NewNode("A")
+	block, _ := FindBlock("NotGate")
+	instance := NewBlockInstance("not", block, []*Node{input}, LevelTransistor)
+	var buffer bytes.Buffer
+	if err := WriteSpiceNetlist(&buffer, []Component{NewInput("A", input, On), instance}, DefaultSpiceOptions); err != nil {
+		t.Fatal(err)
+	}
+	netlist := buffer.String()
+	if !strings.Contains(netlist, " SharedSource 0 DC 5\n") {
+		t.Errorf("expected the shared source to be driven\n%s", netlist)
+	}
+	if strings.Contains(netlist, "SharedGround") {
+		t.Errorf("expected the shared ground to be node 0\n%s", netlist)
+	}
+}
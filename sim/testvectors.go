@@ -0,0 +1,274 @@
+package sim
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Test vector files describe the expected behavior of a design, one row per
+// simulation step:
+//
+//	# half adder
+//	design half_adder.circuit
+//	a b | sum carry
+//	0 0 | 0   0
+//	1 1 | 0   1
+//	- 0 | 1   -
+//	step 2
+//
+// Input columns take 0 or 1, - keeps the previous value and C pulses the
+// input low then high over two steps. Output columns take 0 or 1, x for an
+// undefined node and - when any state is accepted. A step line runs more
+// steps without changing the inputs or checking the outputs. The design line
+// names the design under test, relative to the vector file
+
+type TestVectorRow struct {
+	Line    int
+	Inputs  []string
+	Outputs []string
+	// Steps run by a step line, rows have none
+	Steps int
+}
+
+type TestVectors struct {
+	Design  string
+	Inputs  []string
+	Outputs []string
+	Rows    []TestVectorRow
+}
+
+func validVectorValue(value string, input bool) bool {
+	switch value {
+	case "0", "1", "-":
+		return true
+	case "C":
+		return input
+	case "x", "X":
+		return !input
+	}
+	return false
+}
+
+func ParseTestVectors(r io.Reader) (*TestVectors, error) {
+	vectors := &TestVectors{}
+	header := false
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(strings.ReplaceAll(text, "|", " | "))
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "design":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: design takes a single file", line)
+			}
+			vectors.Design = fields[1]
+			continue
+		case "step":
+			steps := 1
+			if len(fields) > 2 {
+				return nil, fmt.Errorf("line %d: step takes at most a step count", line)
+			}
+			if len(fields) == 2 {
+				var err error
+				if steps, err = strconv.Atoi(fields[1]); err != nil || steps < 1 {
+					return nil, fmt.Errorf("line %d: invalid step count %q", line, fields[1])
+				}
+			}
+			if !header {
+				return nil, fmt.Errorf("line %d: step before the column header", line)
+			}
+			vectors.Rows = append(vectors.Rows, TestVectorRow{Line: line, Steps: steps})
+			continue
+		}
+
+		separator := -1
+		for i, field := range fields {
+			if field == "|" {
+				if separator >= 0 {
+					return nil, fmt.Errorf("line %d: more than one | separator", line)
+				}
+				separator = i
+			}
+		}
+		if separator < 0 {
+			return nil, fmt.Errorf("line %d: missing | between inputs and outputs", line)
+		}
+		inputs, outputs := fields[:separator], fields[separator+1:]
+		if !header {
+			vectors.Inputs, vectors.Outputs = inputs, outputs
+			header = true
+			continue
+		}
+		if len(inputs) != len(vectors.Inputs) || len(outputs) != len(vectors.Outputs) {
+			return nil, fmt.Errorf("line %d: expected %d inputs and %d outputs but got %d and %d",
+				line, len(vectors.Inputs), len(vectors.Outputs), len(inputs), len(outputs))
+		}
+		for i, value := range inputs {
+			if !validVectorValue(value, true) {
+				return nil, fmt.Errorf("line %d: invalid value %q for input %s", line, value, vectors.Inputs[i])
+			}
+		}
+		for i, value := range outputs {
+			if !validVectorValue(value, false) {
+				return nil, fmt.Errorf("line %d: invalid value %q for output %s", line, value, vectors.Outputs[i])
+			}
+		}
+		vectors.Rows = append(vectors.Rows, TestVectorRow{Line: line, Inputs: inputs, Outputs: outputs})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !header {
+		return nil, fmt.Errorf("missing column header")
+	}
+	return vectors, nil
+}
+
+// Row whose outputs differ from the expected ones, with the state of every
+// input and output after the row was applied
+type VectorMismatch struct {
+	Line     int
+	Inputs   []string
+	Outputs  []string
+	Applied  []NodeState
+	Expected []string
+	Actual   []NodeState
+}
+
+func (m VectorMismatch) String() string {
+	var inputs, expected, actual []string
+	for i, input := range m.Inputs {
+		inputs = append(inputs, input+"="+StateDigit(m.Applied[i]))
+	}
+	for i, output := range m.Outputs {
+		expected = append(expected, output+"="+m.Expected[i])
+		actual = append(actual, output+"="+StateDigit(m.Actual[i]))
+	}
+	return fmt.Sprintf("line %d: inputs %s expected %s but got %s",
+		m.Line, strings.Join(inputs, " "), strings.Join(expected, " "), strings.Join(actual, " "))
+}
+
+// Applies every row of vectors to netlist, returning the rows whose outputs
+// did not match. Simulation errors stop the run
+func RunTestVectors(netlist *Netlist, vectors *TestVectors) ([]VectorMismatch, error) {
+	for _, input := range vectors.Inputs {
+		if _, ok := netlist.Terminals[input]; !ok {
+			return nil, fmt.Errorf("design has no input %s", input)
+		}
+	}
+	for _, output := range vectors.Outputs {
+		if _, ok := netlist.Meters[output]; !ok {
+			return nil, fmt.Errorf("design has no output %s", output)
+		}
+	}
+
+	applied := make([]NodeState, len(vectors.Inputs))
+	for i := range applied {
+		applied[i] = Off
+	}
+	step := func(line int) error {
+		for i, input := range vectors.Inputs {
+			netlist.SetInput(input, applied[i])
+		}
+		if err := netlist.Circuit.Tick(); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		return nil
+	}
+
+	var mismatches []VectorMismatch
+	for _, row := range vectors.Rows {
+		if row.Steps > 0 {
+			for range row.Steps {
+				if err := step(row.Line); err != nil {
+					return mismatches, err
+				}
+			}
+			continue
+		}
+
+		pulse := false
+		for i, value := range row.Inputs {
+			switch value {
+			case "0", "1":
+				applied[i] = boolToState(value == "1")
+			case "C":
+				applied[i] = Off
+				pulse = true
+			}
+		}
+		if pulse {
+			if err := step(row.Line); err != nil {
+				return mismatches, err
+			}
+			for i, value := range row.Inputs {
+				if value == "C" {
+					applied[i] = On
+				}
+			}
+		}
+		if err := step(row.Line); err != nil {
+			return mismatches, err
+		}
+
+		mismatch := VectorMismatch{
+			Line:     row.Line,
+			Inputs:   vectors.Inputs,
+			Outputs:  vectors.Outputs,
+			Applied:  append([]NodeState{}, applied...),
+			Expected: row.Outputs,
+		}
+		matches := true
+		for i, output := range vectors.Outputs {
+			state, _ := netlist.Output(output)
+			mismatch.Actual = append(mismatch.Actual, state)
+			switch row.Outputs[i] {
+			case "0", "1":
+				matches = matches && state == boolToState(row.Outputs[i] == "1")
+			case "x", "X":
+				matches = matches && state == Undefined
+			}
+		}
+		if !matches {
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+	return mismatches, nil
+}
+
+// Runs the vector file at path against design, or against the design named
+// by the file when design is empty
+func RunTestVectorFile(path, design string) ([]VectorMismatch, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	vectors, err := ParseTestVectors(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if design == "" {
+		if vectors.Design == "" {
+			return nil, fmt.Errorf("%s: no design given and the file has no design line", path)
+		}
+		design = filepath.Join(filepath.Dir(path), vectors.Design)
+	}
+	netlist, err := LoadDesign(design)
+	if err != nil {
+		return nil, err
+	}
+	mismatches, err := RunTestVectors(netlist, vectors)
+	if err != nil {
+		return mismatches, fmt.Errorf("%s: %w", path, err)
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,77 @@
+package sim
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Runs every vector file in testdata against the design it names, so
+// hardware tests only need a .tv file
+func TestVectorFiles(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.tv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			mismatches, err := RunTestVectorFile(path, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, mismatch := range mismatches {
+				t.Errorf(mismatch.String())
+			}
+		})
+	}
+}
+
+func TestRunTestVectorsMismatch(t *testing.T) {
+	netlist, err := LoadCircuitDescription(strings.NewReader(testHalfAdderDescription))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := ParseTestVectors(strings.NewReader("a b | sum carry\n1 1 | 0 1\n\n0 1 | 0 -\nstep\n1 - | 0 x\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mismatches, err := RunTestVectors(netlist, vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"line 4: inputs a=0 b=1 expected sum=0 carry=- but got sum=1 carry=0",
+		"line 6: inputs a=1 b=1 expected sum=0 carry=x but got sum=0 carry=1",
+	}
+	if len(mismatches) != len(expected) {
+		t.Fatalf("expected %d mismatches but got %v", len(expected), mismatches)
+	}
+	for i, mismatch := range mismatches {
+		if mismatch.String() != expected[i] {
+			t.Errorf("expected %q but got %q", expected[i], mismatch.String())
+		}
+	}
+}
+
+func TestParseTestVectorsErrors(t *testing.T) {
+	tt := []struct {
+		vectors  string
+		expected string
+	}{
+		{"a b\n", "line 1: missing |"},
+		{"a | y | z\n", "line 1: more than one |"},
+		{"a b | y\n0 | 1\n", "line 2: expected 2 inputs and 1 outputs but got 1 and 1"},
+		{"a | y\nx | 1\n", `line 2: invalid value "x" for input a`},
+		{"a | y\n1 | C\n", `line 2: invalid value "C" for output y`},
+		{"step 2\na | y\n", "line 1: step before the column header"},
+		{"a | y\nstep 0\n", `line 2: invalid step count "0"`},
+		{"# nothing\n", "missing column header"},
+	}
+	for _, tc := range tt {
+		_, err := ParseTestVectors(strings.NewReader(tc.vectors))
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("parsing %q returned %v instead of %q", tc.vectors, err, tc.expected)
+		}
+	}
+}
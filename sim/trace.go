@@ -0,0 +1,241 @@
+package sim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+type TraceLevel int
+
+const (
+	TraceOff TraceLevel = iota
+	// Conflicting values driven onto a node
+	TraceError
+	// Meters reading undefined nodes
+	TraceWarn
+	// Meter readings
+	TraceInfo
+	// Components added, acted and deferred
+	TraceDebug
+	// Every node changing state
+	TraceVerbose
+)
+
+var traceLevelNames = []string{"off", "error", "warn", "info", "debug", "verbose"}
+
+func (l TraceLevel) String() string {
+	if l < 0 || int(l) >= len(traceLevelNames) {
+		return "unknown"
+	}
+	return traceLevelNames[l]
+}
+
+func ParseTraceLevel(level string) (TraceLevel, error) {
+	for i, name := range traceLevelNames {
+		if strings.EqualFold(level, name) {
+			return TraceLevel(i), nil
+		}
+	}
+	return TraceOff, fmt.Errorf("unknown trace level %q, use one of %s", level, strings.Join(traceLevelNames, ", "))
+}
+
+type TraceKind string
+
+const (
+	TraceComponentAdded    TraceKind = "component-added"
+	TraceComponentActed    TraceKind = "component-acted"
+	TraceComponentDeferred TraceKind = "component-deferred"
+	TraceNodeChanged       TraceKind = "node-changed"
+	TraceConflict          TraceKind = "conflict"
+	TraceMeterRead         TraceKind = "meter-read"
+)
+
+type TraceEvent struct {
+	Step  uint64
+	Level TraceLevel
+	Kind  TraceKind
+	// Name of the component the event happened in, empty for nodes owned by
+	// no component
+	Component string
+	Node      string
+	// States of the node before and after the event
+	From NodeState
+	To   NodeState
+}
+
+func (e TraceEvent) String() string {
+	var message string
+	switch e.Kind {
+	case TraceComponentAdded:
+		message = fmt.Sprintf("%s added", e.Component)
+	case TraceComponentActed:
+		message = fmt.Sprintf("%s acted", e.Component)
+	case TraceComponentDeferred:
+		message = fmt.Sprintf("%s deferred, its inputs are not ready", e.Component)
+	case TraceNodeChanged:
+		message = fmt.Sprintf("node %s changed from %s to %s", e.Node, StateDigit(e.From), StateDigit(e.To))
+	case TraceConflict:
+		message = fmt.Sprintf("conflict on node %s holding %s, driven to %s", e.Node, StateDigit(e.From), StateDigit(e.To))
+	case TraceMeterRead:
+		message = fmt.Sprintf("meter %s reads %s", e.Component, StateDigit(e.To))
+	default:
+		message = string(e.Kind)
+	}
+	return fmt.Sprintf("step %d: %s", e.Step, message)
+}
+
+// Destination of trace events
+type TraceSink interface {
+	Trace(TraceEvent)
+}
+
+// Discards every event
+type SilentSink struct{}
+
+func (SilentSink) Trace(TraceEvent) {}
+
+// Writes one human-readable line per event
+type TextSink struct {
+	w io.Writer
+}
+
+func NewTextSink(w io.Writer) *TextSink {
+	return &TextSink{w}
+}
+
+func (s *TextSink) Trace(event TraceEvent) {
+	fmt.Fprintf(s.w, "[%s] %s\n", event.Level, event)
+}
+
+// Writes one JSON object per line, for traces analysed by other tools
+type JSONSink struct {
+	encoder *json.Encoder
+}
+
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{json.NewEncoder(w)}
+}
+
+type jsonTraceEvent struct {
+	Step      uint64    `json:"step"`
+	Level     string    `json:"level"`
+	Kind      TraceKind `json:"kind"`
+	Component string    `json:"component,omitempty"`
+	Node      string    `json:"node,omitempty"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to,omitempty"`
+}
+
+func (s *JSONSink) Trace(event TraceEvent) {
+	encoded := jsonTraceEvent{
+		Step:      event.Step,
+		Level:     event.Level.String(),
+		Kind:      event.Kind,
+		Component: event.Component,
+		Node:      event.Node,
+	}
+	switch event.Kind {
+	case TraceNodeChanged, TraceConflict:
+		encoded.From, encoded.To = StateDigit(event.From), StateDigit(event.To)
+	case TraceMeterRead:
+		encoded.To = StateDigit(event.To)
+	}
+	s.encoder.Encode(encoded)
+}
+
+type Tracer struct {
+	Level TraceLevel
+	// Glob patterns of the traced component names, every component is traced
+	// when empty
+	Components []string
+	Sink       TraceSink
+}
+
+func NewTracer(level TraceLevel, sink TraceSink) *Tracer {
+	return &Tracer{Level: level, Sink: sink}
+}
+
+// Copy of the tracer reporting events up to level
+func (t *Tracer) WithLevel(level TraceLevel) *Tracer {
+	copied := *t
+	copied.Level = level
+	return &copied
+}
+
+func (t *Tracer) Enabled(level TraceLevel) bool {
+	if t == nil || t.Sink == nil || level > t.Level {
+		return false
+	}
+	_, silent := t.Sink.(SilentSink)
+	return !silent
+}
+
+func (t *Tracer) Emit(event TraceEvent) {
+	if !t.Enabled(event.Level) {
+		return
+	}
+	if len(t.Components) > 0 {
+		traced := false
+		for _, pattern := range t.Components {
+			if matched, _ := path.Match(pattern, event.Component); matched {
+				traced = true
+				break
+			}
+		}
+		if !traced {
+			return
+		}
+	}
+	t.Sink.Trace(event)
+}
+
+// Tracer of circuits built without one. Its sink is silent until a program
+// sets one, the GUI prints to stdout
+var DefaultTracer = NewTracer(TraceInfo, SilentSink{})
+
+func (r *run) enabled(level TraceLevel) bool {
+	return r != nil && r.tracer.Enabled(level)
+}
+
+func (r *run) trace(level TraceLevel, event TraceEvent) {
+	if !r.enabled(level) {
+		return
+	}
+	event.Level = level
+	event.Step = r.step
+	r.tracer.Emit(event)
+}
+
+// Reports the nodes changed since they were last traced
+func (r *run) traceChanges() {
+	if !r.enabled(TraceVerbose) {
+		return
+	}
+	for i, node := range r.nodes {
+		if node.State == r.traced[i] {
+			continue
+		}
+		r.trace(TraceVerbose, TraceEvent{Kind: TraceNodeChanged, Component: nodeComponentName(node), Node: node.ID, From: r.traced[i], To: node.State})
+		r.traced[i] = node.State
+	}
+}
+
+// Reports the conflict err is caused by, if any, and returns err
+func (r *run) traceConflict(err error) error {
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		r.trace(TraceError, TraceEvent{Kind: TraceConflict, Component: nodeComponentName(conflict.Node), Node: conflict.Node.ID, From: conflict.From, To: conflict.To})
+	}
+	return err
+}
+
+func nodeComponentName(n *Node) string {
+	if n.Parent == nil {
+		return ""
+	}
+	return ComponentName(n.Parent)
+}
@@ -0,0 +1,156 @@
+package sim
+
+import (
+	"strings"
+	"testing"
+)
+
+type recordingSink struct {
+	events []TraceEvent
+}
+
+func (s *recordingSink) Trace(event TraceEvent) {
+	s.events = append(s.events, event)
+}
+
+func (s *recordingSink) kinds() (kinds []TraceKind) {
+	for _, event := range s.events {
+		kinds = append(kinds, event.Kind)
+	}
+	return
+}
+
+func newTracedNotGate(input NodeState, tracer *Tracer) *Circuit {
+	in := NewNode("in")
+	out, notGate := NewNotGate(in)
+	c := NewCircuit([]Component{NewInput("in", in, input), notGate, NewMultimeter("out", out)}, 4, false)
+	c.SetTracer(tracer)
+	return c
+}
+
+func TestTraceLevels(t *testing.T) {
+	tt := []struct {
+		level    TraceLevel
+		expected []TraceKind
+	}{
+		{level: TraceOff},
+		{level: TraceError},
+		{level: TraceInfo, expected: []TraceKind{TraceMeterRead}},
+		// the gate acts, followed by its resistor and transistor
+		{level: TraceDebug, expected: []TraceKind{TraceComponentActed, TraceComponentActed, TraceComponentActed, TraceMeterRead}},
+	}
+	for _, tc := range tt {
+		sink := &recordingSink{}
+		c := newTracedNotGate(On, NewTracer(tc.level, sink))
+		if err := c.Tick(); err != nil {
+			t.Fatal(err)
+		}
+		kinds := sink.kinds()
+		if len(kinds) != len(tc.expected) {
+			t.Fatalf("level %s traced %v instead of %v", tc.level, kinds, tc.expected)
+		}
+		for i := range kinds {
+			if kinds[i] != tc.expected[i] {
+				t.Errorf("level %s traced %v instead of %v", tc.level, kinds, tc.expected)
+			}
+		}
+	}
+}
+
+func TestTraceNodeChanges(t *testing.T) {
+	sink := &recordingSink{}
+	c := newTracedNotGate(Off, NewTracer(TraceVerbose, sink))
+	for range 2 {
+		if err := c.Tick(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	changes := 0
+	for _, event := range sink.events {
+		if event.Kind == TraceNodeChanged {
+			changes++
+			if event.From != Undefined {
+				t.Errorf("node %s changed from %s instead of undefined", event.Node, event.From)
+			}
+		}
+	}
+	if changes == 0 {
+		t.Errorf("expected node changes to be traced")
+	}
+	last := sink.events[len(sink.events)-1]
+	if last.Step != 1 || last.Kind != TraceMeterRead || last.To != On {
+		t.Errorf("expected the meter to read 1 on step 1 but got %s", last)
+	}
+}
+
+func TestTraceConflict(t *testing.T) {
+	node := NewNode("shared")
+	sink := &recordingSink{}
+	c := NewCircuit([]Component{NewInput("high", node, On), NewInput("low", node, Off)}, 4, false)
+	c.SetTracer(NewTracer(TraceError, sink))
+	if err := c.Tick(); err == nil {
+		t.Fatalf("expected conflicting inputs to fail")
+	}
+	if len(sink.events) != 1 {
+		t.Fatalf("expected a single conflict but got %v", sink.kinds())
+	}
+	event := sink.events[0]
+	if event.Kind != TraceConflict || event.Level != TraceError || event.From != On || event.To != Off {
+		t.Errorf("unexpected conflict event %s", event)
+	}
+}
+
+func TestTraceComponentFilter(t *testing.T) {
+	sink := &recordingSink{}
+	tracer := NewTracer(TraceDebug, sink)
+	tracer.Components = []string{"o*"}
+	c := newTracedNotGate(On, tracer)
+	if err := c.Tick(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.events) != 1 || sink.events[0].Component != "out" {
+		t.Errorf("expected only the out meter to be traced but got %v", sink.events)
+	}
+}
+
+func TestTraceSinks(t *testing.T) {
+	events := []TraceEvent{
+		{Step: 2, Level: TraceError, Kind: TraceConflict, Node: "n1", From: On, To: Off},
+		{Step: 3, Level: TraceInfo, Kind: TraceMeterRead, Component: "sum", Node: "n2", To: On},
+	}
+
+	var text strings.Builder
+	textSink := NewTextSink(&text)
+	var jsonLines strings.Builder
+	jsonSink := NewJSONSink(&jsonLines)
+	for _, event := range events {
+		textSink.Trace(event)
+		jsonSink.Trace(event)
+	}
+
+	expectedText := "[error] step 2: conflict on node n1 holding 1, driven to 0\n" +
+		"[info] step 3: meter sum reads 1\n"
+	if text.String() != expectedText {
+		t.Errorf("expected text trace\n%s\nbut got\n%s", expectedText, text.String())
+	}
+	expectedJSON := `{"step":2,"level":"error","kind":"conflict","node":"n1","from":"1","to":"0"}` + "\n" +
+		`{"step":3,"level":"info","kind":"meter-read","component":"sum","node":"n2","to":"1"}` + "\n"
+	if jsonLines.String() != expectedJSON {
+		t.Errorf("expected JSON trace\n%s\nbut got\n%s", expectedJSON, jsonLines.String())
+	}
+}
+
+func TestParseTraceLevel(t *testing.T) {
+	for _, name := range []string{"off", "error", "warn", "info", "debug", "verbose"} {
+		level, err := ParseTraceLevel(strings.ToUpper(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if level.String() != name {
+			t.Errorf("parsed %s as %s", name, level)
+		}
+	}
+	if _, err := ParseTraceLevel("loud"); err == nil {
+		t.Errorf("expected an unknown level to fail")
+	}
+}
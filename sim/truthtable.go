@@ -0,0 +1,205 @@
+package sim
+
+import (
+	"encoding/csv"
+	"fmt"
+	"go/format"
+	"io"
+	"math/rand"
+	"strings"
+	"unicode"
+)
+
+type TruthTableRow struct {
+	Inputs  []NodeState
+	Outputs []NodeState
+}
+
+type TruthTable struct {
+	Name    string
+	Inputs  []string
+	Outputs []string
+	Rows    []TruthTableRow
+	// Whether the rows are a random subset of the input combinations
+	Sampled bool
+}
+
+// Input states of the combination number vector, the first input being the
+// most significant bit so rows read like counting in binary
+func combinationStates(vector uint64, inputCount int) []NodeState {
+	states := make([]NodeState, inputCount)
+	for i := range states {
+		states[i] = boolToState(vector&(1<<(inputCount-1-i)) != 0)
+	}
+	return states
+}
+
+func (n *Netlist) simulate(inputs []NodeState) ([]NodeState, error) {
+	for i, input := range n.Inputs {
+		if err := n.SetInput(input, inputs[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := n.Circuit.Tick(); err != nil {
+		return nil, fmt.Errorf("inputs %s: %w", formatStates(inputs), err)
+	}
+	outputs := make([]NodeState, len(n.Outputs))
+	for i, output := range n.Outputs {
+		outputs[i], _ = n.Output(output)
+	}
+	return outputs, nil
+}
+
+// Simulates netlist for every combination of its inputs. When there are more
+// than samples combinations, samples distinct random combinations are
+// simulated instead. A non positive samples always enumerates every
+// combination, which is refused above maxExhaustiveInputs inputs
+func GenerateTruthTable(netlist *Netlist, samples int, rng *rand.Rand) (*TruthTable, error) {
+	table := &TruthTable{Name: netlist.Name, Inputs: netlist.Inputs, Outputs: netlist.Outputs}
+	inputCount := len(netlist.Inputs)
+	if samples <= 0 && inputCount > maxExhaustiveInputs {
+		return nil, fmt.Errorf("%d inputs are more than the %d whose combinations can all be simulated, sample them instead", inputCount, maxExhaustiveInputs)
+	}
+	exhaustive := samples <= 0 || inputCount <= maxExhaustiveInputs && 1<<inputCount <= samples
+
+	var combinations [][]NodeState
+	if exhaustive {
+		for vector := range uint64(1) << inputCount {
+			combinations = append(combinations, combinationStates(vector, inputCount))
+		}
+	} else {
+		table.Sampled = true
+		seen := map[string]bool{}
+		for len(combinations) < samples {
+			inputs := make([]NodeState, inputCount)
+			for i := range inputs {
+				inputs[i] = boolToState(rng.Intn(2) == 1)
+			}
+			if key := formatStates(inputs); !seen[key] {
+				seen[key] = true
+				combinations = append(combinations, inputs)
+			}
+		}
+	}
+
+	for _, inputs := range combinations {
+		outputs, err := netlist.simulate(inputs)
+		if err != nil {
+			return nil, err
+		}
+		table.Rows = append(table.Rows, TruthTableRow{inputs, outputs})
+	}
+	return table, nil
+}
+
+func (t *TruthTable) WriteMarkdown(w io.Writer) error {
+	var builder strings.Builder
+	header := append(append([]string{}, t.Inputs...), t.Outputs...)
+	builder.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	builder.WriteString("|" + strings.Repeat(" --- |", len(header)) + "\n")
+	for _, row := range t.Rows {
+		var cells []string
+		for _, state := range append(append([]NodeState{}, row.Inputs...), row.Outputs...) {
+			cells = append(cells, StateDigit(state))
+		}
+		builder.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func (t *TruthTable) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write(append(append([]string{}, t.Inputs...), t.Outputs...))
+	for _, row := range t.Rows {
+		var cells []string
+		for _, state := range append(append([]NodeState{}, row.Inputs...), row.Outputs...) {
+			cells = append(cells, StateDigit(state))
+		}
+		writer.Write(cells)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Go field name for a port, in the lower camel case of the hand-written tests
+func goFieldName(prefix, name string) string {
+	var words []string
+	word := ""
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word += string(r)
+		} else if word != "" {
+			words = append(words, word)
+			word = ""
+		}
+	}
+	if word != "" {
+		words = append(words, word)
+	}
+	if prefix != "" {
+		words = append([]string{prefix}, words...)
+	}
+	if len(words) == 0 {
+		return "port"
+	}
+	field := strings.ToLower(words[0][:1]) + words[0][1:]
+	for _, word := range words[1:] {
+		field += strings.ToUpper(word[:1]) + word[1:]
+	}
+	if unicode.IsDigit(rune(field[0])) {
+		field = "n" + field
+	}
+	return field
+}
+
+func goStateName(state NodeState) string {
+	switch state {
+	case On:
+		return "On"
+	case Off:
+		return "Off"
+	}
+	return "Undefined"
+}
+
+// Writes the table as a Go test table, in the style of the gate tests
+func (t *TruthTable) WriteGoTestTable(w io.Writer) error {
+	var fields []string
+	for _, input := range t.Inputs {
+		fields = append(fields, goFieldName("", input))
+	}
+	for _, output := range t.Outputs {
+		fields = append(fields, goFieldName("expected", output))
+	}
+	seen := map[string]bool{}
+	for i, field := range fields {
+		name := field
+		for j := 2; seen[name]; j++ {
+			name = fmt.Sprintf("%s%d", field, j)
+		}
+		seen[name] = true
+		fields[i] = name
+	}
+
+	var builder strings.Builder
+	builder.WriteString("tt := []struct {\n")
+	for _, field := range fields {
+		builder.WriteString(field + " NodeState\n")
+	}
+	builder.WriteString("}{\n")
+	for _, row := range t.Rows {
+		var values []string
+		for i, state := range append(append([]NodeState{}, row.Inputs...), row.Outputs...) {
+			values = append(values, fields[i]+": "+goStateName(state))
+		}
+		builder.WriteString("{" + strings.Join(values, ", ") + "},\n")
+	}
+	builder.WriteString("}\n")
+	source, err := format.Source([]byte(builder.String()))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(source)
+	return err
+}
@@ -0,0 +1,127 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestGenerateTruthTable(t *testing.T) {
+	netlist, err := LoadCircuitDescription(strings.NewReader(testHalfAdderDescription))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := GenerateTruthTable(netlist, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		write    func(*TruthTable, *strings.Builder) error
+		expected string
+	}{
+		{
+			write: func(table *TruthTable, w *strings.Builder) error { return table.WriteMarkdown(w) },
+			expected: "| a | b | sum | carry |\n" +
+				"| --- | --- | --- | --- |\n" +
+				"| 0 | 0 | 0 | 0 |\n" +
+				"| 0 | 1 | 1 | 0 |\n" +
+				"| 1 | 0 | 1 | 0 |\n" +
+				"| 1 | 1 | 0 | 1 |\n",
+		},
+		{
+			write:    func(table *TruthTable, w *strings.Builder) error { return table.WriteCSV(w) },
+			expected: "a,b,sum,carry\n0,0,0,0\n0,1,1,0\n1,0,1,0\n1,1,0,1\n",
+		},
+		{
+			write: func(table *TruthTable, w *strings.Builder) error { return table.WriteGoTestTable(w) },
+			expected: "tt := []struct {\n" +
+				"\ta             NodeState\n" +
+				"\tb             NodeState\n" +
+				"\texpectedSum   NodeState\n" +
+				"\texpectedCarry NodeState\n" +
+				"}{\n" +
+				"\t{a: Off, b: Off, expectedSum: Off, expectedCarry: Off},\n" +
+				"\t{a: Off, b: On, expectedSum: On, expectedCarry: Off},\n" +
+				"\t{a: On, b: Off, expectedSum: On, expectedCarry: Off},\n" +
+				"\t{a: On, b: On, expectedSum: Off, expectedCarry: On},\n" +
+				"}\n",
+		},
+	}
+	for _, tc := range tt {
+		var builder strings.Builder
+		if err := tc.write(table, &builder); err != nil {
+			t.Fatal(err)
+		}
+		if builder.String() != tc.expected {
+			t.Errorf("expected\n%s\nbut got\n%s", tc.expected, builder.String())
+		}
+	}
+}
+
+func TestGenerateTruthTableSampled(t *testing.T) {
+	description := "input a, b, c, d, e, f\noutput y\nx0 = AndGate(a, b) @behavioral\nx1 = AndGate(c, d) @behavioral\nx2 = AndGate(e, f) @behavioral\n" +
+		"x3 = AndGate(x0, x1) @behavioral\nx4 = AndGate(x3, x2) @behavioral\ny = x4\n"
+	netlist, err := LoadCircuitDescription(strings.NewReader(description))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := GenerateTruthTable(netlist, 10, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !table.Sampled || len(table.Rows) != 10 {
+		t.Fatalf("expected 10 sampled rows but got %d", len(table.Rows))
+	}
+	seen := map[string]bool{}
+	for _, row := range table.Rows {
+		key := formatStates(row.Inputs)
+		if seen[key] {
+			t.Errorf("input combination %s was sampled twice", key)
+		}
+		seen[key] = true
+		expected := NodeState(Off)
+		if !strings.Contains(key, "off") {
+			expected = On
+		}
+		if row.Outputs[0] != expected {
+			t.Errorf("inputs %s generated %s instead of %s", key, row.Outputs[0], expected)
+		}
+	}
+}
+
+func TestGenerateTruthTableRejectsWideNetlists(t *testing.T) {
+	inputs := make([]string, maxExhaustiveInputs+1)
+	for i := range inputs {
+		inputs[i] = fmt.Sprintf("i%d", i)
+	}
+	description := "input " + strings.Join(inputs, ", ") + "\noutput y\ny = i0\n"
+	netlist, err := LoadCircuitDescription(strings.NewReader(description))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		samples       int
+		expectedError bool
+	}{
+		{samples: 0, expectedError: true},
+		{samples: -1, expectedError: true},
+		{samples: 8, expectedError: false},
+	}
+	for _, tc := range tt {
+		table, err := GenerateTruthTable(netlist, tc.samples, rand.New(rand.NewSource(1)))
+		if tc.expectedError {
+			if err == nil {
+				t.Errorf("expected %d samples of %d inputs to be rejected", tc.samples, len(inputs))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d samples: %v", tc.samples, err)
+		} else if len(table.Rows) != tc.samples {
+			t.Errorf("expected %d rows but got %d", tc.samples, len(table.Rows))
+		}
+	}
+}
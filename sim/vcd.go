@@ -0,0 +1,266 @@
+package sim
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Signal recorded to a VCD file, a bus when it has more than one node
+type vcdSignal struct {
+	scope []string
+	name  string
+	// least significant bit first
+	nodes []*Node
+	code  string
+
+	last    string
+	changes []vcdChange
+}
+
+type vcdChange struct {
+	time  uint64
+	value string
+}
+
+// Records the state of selected nodes on every simulation step and writes
+// them as a Value Change Dump
+type VCDRecorder struct {
+	Timescale string
+	signals   []*vcdSignal
+	lastTime  uint64
+}
+
+func NewVCDRecorder() *VCDRecorder {
+	return &VCDRecorder{Timescale: "1ns"}
+}
+
+// Short identifiers made of printable ASCII characters, as used by VCD
+func vcdCode(index int) string {
+	code := ""
+	for {
+		code += string(rune('!' + index%94))
+		index /= 94
+		if index == 0 {
+			return code
+		}
+		index--
+	}
+}
+
+// Records nodes as a signal called name inside scope, a dot separated path
+// such as "top.alu.adder0". Multiple nodes are grouped into a bus, least
+// significant bit first
+func (r *VCDRecorder) Watch(scope, name string, nodes ...*Node) {
+	var path []string
+	for _, part := range strings.Split(scope, ".") {
+		if part != "" {
+			path = append(path, sanitizeNetName(part))
+		}
+	}
+	if len(path) == 0 {
+		path = []string{"top"}
+	}
+	// names must be unique inside a scope
+	base := sanitizeNetName(name)
+	name = base
+	for i := 2; r.declared(path, name); i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	r.signals = append(r.signals, &vcdSignal{
+		scope: path,
+		name:  name,
+		nodes: nodes,
+		code:  vcdCode(len(r.signals)),
+	})
+}
+
+func (r *VCDRecorder) declared(scope []string, name string) bool {
+	for _, signal := range r.signals {
+		if signal.name == name && slices.Equal(signal.scope, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Records every meter of the circuit under scope
+func (r *VCDRecorder) WatchMeters(c *Circuit, scope string) {
+	for _, meter := range c.meters {
+		m := meter.(*Meter)
+		r.Watch(scope, componentName(m.ComponentID, m.Node), m.Node)
+	}
+}
+
+// Records the ports of component c inside a scope named after it, and the
+// ports of its nested components in nested scopes down to depth levels
+func (r *VCDRecorder) WatchComponent(scope string, c Component, depth int) {
+	label := ComponentLabel(c)
+	if instance, ok := c.(*BlockInstance); ok {
+		label = instance.Name
+	}
+	if scope == "" {
+		scope = "top"
+	}
+	r.watchComponent(scope+"."+label, c, depth)
+}
+
+func (r *VCDRecorder) watchComponent(scope string, c Component, depth int) {
+	inputs, outputs := Ports(c)
+	for i, node := range slices.Concat(inputs, outputs) {
+		name := node.ID
+		if name == "" {
+			name = fmt.Sprintf("port%d", i)
+		}
+		r.Watch(scope, name, node)
+	}
+	if depth <= 0 {
+		return
+	}
+	for i, subcomponent := range Subcomponents(c) {
+		if Subcomponents(subcomponent) == nil {
+			continue
+		}
+		r.watchComponent(fmt.Sprintf("%s.%s_%d", scope, ComponentLabel(subcomponent), i), subcomponent, depth-1)
+	}
+}
+
+// State as a single digit, 1, 0 or x
+func StateDigit(state NodeState) string {
+	return string(vcdBit(state))
+}
+
+func vcdBit(state NodeState) byte {
+	switch state {
+	case On:
+		return '1'
+	case Off:
+		return '0'
+	default:
+		return 'x'
+	}
+}
+
+func (s *vcdSignal) value() string {
+	bits := make([]byte, len(s.nodes))
+	for i, node := range s.nodes {
+		bits[len(s.nodes)-1-i] = vcdBit(node.State)
+	}
+	if len(bits) == 1 {
+		return string(bits) + s.code
+	}
+	return "b" + string(bits) + " " + s.code
+}
+
+// Captures the current state of every watched signal at time
+func (r *VCDRecorder) Sample(time uint64) {
+	for _, signal := range r.signals {
+		value := signal.value()
+		if len(signal.changes) == 0 || value != signal.last {
+			signal.changes = append(signal.changes, vcdChange{time, value})
+			signal.last = value
+		}
+	}
+	r.lastTime = time
+}
+
+func (r *VCDRecorder) WriteTo(w io.Writer) (int64, error) {
+	var builder strings.Builder
+	builder.WriteString("$version copooter $end\n")
+	fmt.Fprintf(&builder, "$timescale %s $end\n", r.Timescale)
+
+	// signals are grouped by scope so every scope is opened once
+	var current []string
+	for _, signal := range r.sortedByScope() {
+		common := 0
+		for common < len(current) && common < len(signal.scope) && current[common] == signal.scope[common] {
+			common++
+		}
+		for range len(current) - common {
+			builder.WriteString("$upscope $end\n")
+		}
+		for _, scope := range signal.scope[common:] {
+			fmt.Fprintf(&builder, "$scope module %s $end\n", scope)
+		}
+		current = signal.scope
+		if len(signal.nodes) == 1 {
+			fmt.Fprintf(&builder, "$var wire 1 %s %s $end\n", signal.code, signal.name)
+		} else {
+			fmt.Fprintf(&builder, "$var wire %d %s %s [%d:0] $end\n", len(signal.nodes), signal.code, signal.name, len(signal.nodes)-1)
+		}
+	}
+	for range current {
+		builder.WriteString("$upscope $end\n")
+	}
+	builder.WriteString("$enddefinitions $end\n")
+
+	// merge the changes of every signal in time order
+	indexes := make([]int, len(r.signals))
+	first := true
+	for {
+		time, found := uint64(0), false
+		for i, signal := range r.signals {
+			if indexes[i] < len(signal.changes) && (!found || signal.changes[indexes[i]].time < time) {
+				time, found = signal.changes[indexes[i]].time, true
+			}
+		}
+		if !found {
+			break
+		}
+		fmt.Fprintf(&builder, "#%d\n", time)
+		if first {
+			builder.WriteString("$dumpvars\n")
+		}
+		for i, signal := range r.signals {
+			if indexes[i] < len(signal.changes) && signal.changes[indexes[i]].time == time {
+				builder.WriteString(signal.changes[indexes[i]].value + "\n")
+				indexes[i]++
+			}
+		}
+		if first {
+			builder.WriteString("$end\n")
+			first = false
+		}
+	}
+	if !first {
+		fmt.Fprintf(&builder, "#%d\n", r.lastTime+1)
+	}
+	n, err := io.WriteString(w, builder.String())
+	return int64(n), err
+}
+
+// Stable ordering of signals that keeps signals of the same scope together
+func (r *VCDRecorder) sortedByScope() []*vcdSignal {
+	var sorted []*vcdSignal
+	placed := map[*vcdSignal]bool{}
+	var place func(prefix []string)
+	place = func(prefix []string) {
+		for _, signal := range r.signals {
+			if !placed[signal] && strings.Join(signal.scope, ".") == strings.Join(prefix, ".") {
+				placed[signal] = true
+				sorted = append(sorted, signal)
+			}
+		}
+		var children []string
+		seen := map[string]bool{}
+		for _, signal := range r.signals {
+			if placed[signal] || len(signal.scope) <= len(prefix) {
+				continue
+			}
+			if strings.Join(signal.scope[:len(prefix)], ".") != strings.Join(prefix, ".") {
+				continue
+			}
+			child := signal.scope[len(prefix)]
+			if !seen[child] {
+				seen[child] = true
+				children = append(children, child)
+			}
+		}
+		for _, child := range children {
+			place(append(append([]string{}, prefix...), child))
+		}
+	}
+	place(nil)
+	return sorted
+}
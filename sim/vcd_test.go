@@ -0,0 +1,82 @@
+package sim
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVCDRecorder(t *testing.T) {
+	input1 := NewNode("A")
+	input2 := NewNode("B")
+	terminal1 := NewInput("A", input1, Off)
+	terminal2 := NewInput("B", input2, On)
+	out, carry, adder := NewSimpleAdder(input1, input2)
+	meter := NewMultimeter("sum", out)
+
+	c := NewCircuit([]Component{terminal1, terminal2, adder, meter}, 4, false)
+	recorder := NewVCDRecorder()
+	recorder.Watch("top", "inputs", input1, input2)
+	recorder.Watch("top.adder", "carry", carry)
+	recorder.WatchMeters(c, "top")
+	c.Record(recorder)
+
+	states := []NodeState{Off, On, On}
+	for _, state := range states {
+		terminal1.state = state
+		if err := c.Tick(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var builder strings.Builder
+	if _, err := recorder.WriteTo(&builder); err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		"$version copooter $end",
+		"$timescale 1ns $end",
+		"$scope module top $end",
+		"$var wire 2 ! inputs [1:0] $end",
+		"$var wire 1 # sum $end",
+		"$scope module adder $end",
+		"$var wire 1 \" carry $end",
+		"$upscope $end",
+		"$upscope $end",
+		"$enddefinitions $end",
+		"#0",
+		"$dumpvars",
+		"b10 !",
+		"0\"",
+		"1#",
+		"$end",
+		"#1",
+		"b11 !",
+		"1\"",
+		"0#",
+		"#3",
+		"",
+	}, "\n")
+	if builder.String() != expected {
+		t.Errorf("expected dump\n%s\nbut got\n%s", expected, builder.String())
+	}
+}
+
+func TestVCDRecorderComponentScopes(t *testing.T) {
+	_, _, adder := NewFullAdder(NewNode("A"), NewNode("B"), NewNode("Cin"))
+	recorder := NewVCDRecorder()
+	recorder.WatchComponent("", adder, 1)
+
+	var builder strings.Builder
+	if _, err := recorder.WriteTo(&builder); err != nil {
+		t.Fatal(err)
+	}
+	dump := builder.String()
+	for _, expected := range []string{"$scope module top $end", "$scope module FullAdder $end", "$scope module XorGate_0 $end", "$var wire 1 + AndGate_AndOutput_2 $end"} {
+		if !strings.Contains(dump, expected) {
+			t.Errorf("expected dump to contain %q but got\n%s", expected, dump)
+		}
+	}
+	if strings.Count(dump, "$scope") != strings.Count(dump, "$upscope") {
+		t.Errorf("unbalanced scopes in dump\n%s", dump)
+	}
+}
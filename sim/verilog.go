@@ -0,0 +1,276 @@
+package sim
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Identifies the net of every node it is asked about, nodes in the same net
+// share the same index
+type netIndex struct {
+	nets map[*Node]int
+	next int
+}
+
+func newNetIndex() *netIndex {
+	return &netIndex{nets: map[*Node]int{}}
+}
+
+func (n *netIndex) of(node *Node) int {
+	if net, ok := n.nets[node]; ok {
+		return net
+	}
+	net := n.next
+	n.next++
+	for member := range ConnectedNodes(node) {
+		n.nets[member] = net
+	}
+	return net
+}
+
+// Names given to nets inside a single Verilog module
+type verilogModule struct {
+	nets    *netIndex
+	names   map[int]string
+	used    map[string]bool
+	inputs  []string
+	outputs []string
+	wires   []string
+	body    []string
+}
+
+var verilogKeywords = map[string]bool{
+	"module": true, "endmodule": true, "input": true, "output": true, "wire": true,
+	"supply0": true, "supply1": true, "nmos": true, "pmos": true, "pullup": true,
+	"pulldown": true, "rtran": true, "tran": true, "assign": true, "vdd": true, "gnd": true,
+}
+
+func verilogIdentifier(name string) string {
+	name = sanitizeNetName(name)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "n" + name
+	}
+	return name
+}
+
+func (m *verilogModule) unique(base string) string {
+	base = verilogIdentifier(base)
+	name := base
+	for i := 2; m.used[name] || verilogKeywords[name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	m.used[name] = true
+	return name
+}
+
+// Names the port of node, the first port of a net also names the net. Later
+// ports and ports on a supply are joined to the net with connect, formatted
+// with the net and the port names
+func (m *verilogModule) port(node *Node, connect string) string {
+	name := m.unique(node.ID)
+	net := m.nets.of(node)
+	if existing, ok := m.names[net]; ok {
+		m.body = append(m.body, fmt.Sprintf(connect, existing, name))
+	} else {
+		m.names[net] = name
+	}
+	return name
+}
+
+// Returns the name of the net of node inside the module, declaring a wire
+// for nets that are neither ports nor supplies
+func (m *verilogModule) net(node *Node) string {
+	net := m.nets.of(node)
+	if name, ok := m.names[net]; ok {
+		return name
+	}
+	var free, owned []string
+	for member := range ConnectedNodes(node) {
+		if member.ID == "" {
+			continue
+		}
+		if member.Parent == nil {
+			free = append(free, member.ID)
+		} else {
+			owned = append(owned, member.ID)
+		}
+	}
+	slices.Sort(free)
+	slices.Sort(owned)
+	base := "net"
+	if len(free) > 0 {
+		base = free[0]
+	} else if len(owned) > 0 {
+		base = owned[0]
+	}
+	name := m.unique(base)
+	m.names[net] = name
+	m.wires = append(m.wires, name)
+	return name
+}
+
+// Whether a transistor channel chain starting at net reaches the ground
+// rail, without going through the transistor being oriented
+func (m *verilogModule) reachesGround(net int, transistors []*Transistor, skip *Transistor) bool {
+	ground := m.nets.of(SharedGroundNode)
+	visited := map[int]bool{}
+	pending := []int{net}
+	for len(pending) > 0 {
+		current := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if current == ground {
+			return true
+		}
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+		for _, t := range transistors {
+			if t == skip {
+				continue
+			}
+			source, drain := m.nets.of(t.Source), m.nets.of(t.Drain)
+			if source == current {
+				pending = append(pending, drain)
+			} else if drain == current {
+				pending = append(pending, source)
+			}
+		}
+	}
+	return false
+}
+
+// Verilog exporter for the custom component hierarchy. Every component type
+// becomes a single module, emitted after the modules it instantiates
+type verilogWriter struct {
+	nets    *netIndex
+	emitted map[string]bool
+	modules []string
+}
+
+func customComponentOf(c Component) (*CustomComponent, error) {
+	switch c := c.(type) {
+	case *CustomComponent:
+		return c, nil
+	case *BlockInstance:
+		if c.Level != LevelTransistor {
+			return nil, fmt.Errorf("block %s is simulated behaviorally, switch it to transistor level to export it", c.Name)
+		}
+		return customComponentOf(c.implementation(LevelTransistor).component)
+	case *FunctionComponent:
+		return nil, fmt.Errorf("behavioral component %s has no structural description", c.ComponentType)
+	}
+	return nil, nil
+}
+
+func (v *verilogWriter) module(c *CustomComponent) error {
+	if v.emitted[c.ComponentType] {
+		return nil
+	}
+	v.emitted[c.ComponentType] = true
+
+	m := &verilogModule{
+		nets:  v.nets,
+		names: map[int]string{},
+		used:  map[string]bool{},
+	}
+	m.names[v.nets.of(SharedSourceNode)] = "vdd"
+	m.names[v.nets.of(SharedGroundNode)] = "gnd"
+	for _, input := range c.Inputs {
+		m.inputs = append(m.inputs, m.port(input, "  tran (%s, %s);"))
+	}
+	for _, output := range c.Outputs {
+		m.outputs = append(m.outputs, m.port(output, "  assign %[2]s = %[1]s;"))
+	}
+
+	var transistors []*Transistor
+	for _, subcomponent := range c.Subcomponents {
+		if t, ok := subcomponent.(*Transistor); ok {
+			transistors = append(transistors, t)
+		}
+	}
+
+	for _, subcomponent := range c.Subcomponents {
+		switch s := subcomponent.(type) {
+		case *Transistor:
+			// the simulator drives On from source to drain and Off from drain
+			// to source, so pull-down transistors output on their source
+			out, data := m.net(s.Drain), m.net(s.Source)
+			if m.reachesGround(v.nets.of(s.Drain), transistors, s) {
+				out, data = data, out
+			}
+			m.body = append(m.body, fmt.Sprintf("  nmos %s (%s, %s, %s);", m.unique("t_"+out), out, data, m.net(s.Gate)))
+		case *Resistor:
+			node1, node2 := m.net(s.Node1), m.net(s.Node2)
+			switch {
+			case node1 == "vdd":
+				m.body = append(m.body, fmt.Sprintf("  pullup (%s);", node2))
+			case node2 == "vdd":
+				m.body = append(m.body, fmt.Sprintf("  pullup (%s);", node1))
+			case node1 == "gnd":
+				m.body = append(m.body, fmt.Sprintf("  pulldown (%s);", node2))
+			case node2 == "gnd":
+				m.body = append(m.body, fmt.Sprintf("  pulldown (%s);", node1))
+			default:
+				m.body = append(m.body, fmt.Sprintf("  rtran (%s, %s);", node1, node2))
+			}
+		default:
+			child, err := customComponentOf(subcomponent)
+			if err != nil {
+				return err
+			}
+			if child == nil {
+				return fmt.Errorf("component %s cannot be exported to Verilog", subcomponent.Debug())
+			}
+			if err := v.module(child); err != nil {
+				return err
+			}
+			var ports []string
+			for _, node := range child.Nodes() {
+				ports = append(ports, m.net(node))
+			}
+			name := child.ComponentType
+			if len(child.Outputs) > 0 {
+				name = m.net(child.Outputs[0])
+			}
+			m.body = append(m.body, fmt.Sprintf("  %s %s (%s);", child.ComponentType, m.unique("u_"+name), strings.Join(ports, ", ")))
+		}
+	}
+
+	lines := []string{fmt.Sprintf("module %s (%s);", verilogIdentifier(c.ComponentType), strings.Join(append(m.inputs, m.outputs...), ", "))}
+	for _, input := range m.inputs {
+		lines = append(lines, "  input "+input+";")
+	}
+	for _, output := range m.outputs {
+		lines = append(lines, "  output "+output+";")
+	}
+	lines = append(lines, "  supply1 vdd;", "  supply0 gnd;")
+	for _, wire := range m.wires {
+		lines = append(lines, "  wire "+wire+";")
+	}
+	lines = append(lines, "")
+	lines = append(lines, m.body...)
+	lines = append(lines, "endmodule")
+	v.modules = append(v.modules, strings.Join(lines, "\n"))
+	return nil
+}
+
+// Writes top and every component type nested in it as structural Verilog
+// modules, with transistors as switch-level primitives
+func WriteVerilog(w io.Writer, top Component) error {
+	c, err := customComponentOf(top)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return fmt.Errorf("component %s is not a custom component", top.Debug())
+	}
+	v := &verilogWriter{nets: newNetIndex(), emitted: map[string]bool{}}
+	if err := v.module(c); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, strings.Join(v.modules, "\n\n")+"\n")
+	return err
+}
@@ -0,0 +1,81 @@
+package sim
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestVerilogXorGate(t *testing.T) {
+	_, xorGate := NewXorGate(NewNode("a"), NewNode("b"))
+
+	var buffer bytes.Buffer
+	if err := WriteVerilog(&buffer, xorGate); err != nil {
+		t.Fatal(err)
+	}
+	verilog := buffer.String()
+
+	// submodules must be declared before the modules that instantiate them
+	order := []string{"module OrGate (", "module NandGate (", "module AndGate (", "module XorGate ("}
+	last := -1
+	for _, declaration := range order {
+		index := strings.Index(verilog, declaration)
+		if index < last {
+			t.Errorf("expected %q after the previous modules\n%s", declaration, verilog)
+		}
+		last = index
+	}
+	if strings.Count(verilog, "module AndGate (") != 1 {
+		t.Errorf("expected AndGate to be declared once\n%s", verilog)
+	}
+
+	expectedLines := []string{
+		"  nmos t_OrGate_OrOutput (OrGate_OrOutput, vdd, a);",
+		"  pulldown (OrGate_OrOutput);",
+		"  nmos t_NandOutput (NandOutput, NandIntermediate, a);",
+		"  nmos t_NandIntermediate (NandIntermediate, gnd, b);",
+		"  pullup (NandOutput);",
+		"  OrGate u_OrGate_OrOutput (a, b, OrGate_OrOutput);",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(verilog, line+"\n") {
+			t.Errorf("expected Verilog to contain %q\n%s", line, verilog)
+		}
+	}
+}
+
+func TestVerilogRejectsBehavioralComponents(t *testing.T) {
+	_, notGate := NewBehavioralNotGate(NewNode("a"))
+	if err := WriteVerilog(&bytes.Buffer{}, notGate); err == nil {
+		t.Errorf("expected behavioral components to be rejected")
+	}
+}
+
+func TestVerilogSharedPorts(t *testing.T) {
+	a, b, y := NewNode("a"), NewNode("b"), NewNode("y")
+	a.Connect(b)
+	y.Connect(a)
+	tied := NewCustomComponent("Tied", nil, []*Node{a, b, SharedSourceNode}, []*Node{y, SharedGroundNode})
+
+	var buffer bytes.Buffer
+	if err := WriteVerilog(&buffer, tied); err != nil {
+		t.Fatal(err)
+	}
+	expected := "module Tied (a, b, SharedSource, y, SharedGround);\n" +
+		"  input a;\n" +
+		"  input b;\n" +
+		"  input SharedSource;\n" +
+		"  output y;\n" +
+		"  output SharedGround;\n" +
+		"  supply1 vdd;\n" +
+		"  supply0 gnd;\n" +
+		"\n" +
+		"  tran (a, b);\n" +
+		"  tran (vdd, SharedSource);\n" +
+		"  assign y = a;\n" +
+		"  assign SharedGround = gnd;\n" +
+		"endmodule\n"
+	if actual := buffer.String(); actual != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, actual)
+	}
+}
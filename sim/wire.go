@@ -0,0 +1,302 @@
+package sim
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+)
+
+var (
+	// Grid steps searched around the ends of a wire leg when routing it
+	RoutingMargin = int32(12)
+	// Extra cost of every grid step taken through a component body, high
+	// enough that wires only cross one when leaving their own component
+	obstaclePenalty = 50
+	// Extra cost of every bend, so routes prefer straight runs
+	bendPenalty = 3
+)
+
+// Connection between the nodes of two components drawn on the schematic,
+// passing through the waypoints placed by the user
+type Wire struct {
+	From      *Node
+	To        *Node
+	Waypoints []Position
+}
+
+func NewWire(from, to *Node, waypoints ...Position) *Wire {
+	return &Wire{From: from, To: to, Waypoints: waypoints}
+}
+
+// Joins the nodes of the wire
+func (w *Wire) Connect() {
+	w.From.Connect(w.To)
+}
+
+func (w *Wire) Disconnect() {
+	w.From.Disconnect(w.To)
+}
+
+// Whether n is one of the ends of the wire
+func (w *Wire) Touches(n *Node) bool {
+	return w.From == n || w.To == n
+}
+
+// Axis aligned rectangle, such as the body of a component wires are routed
+// around
+type Rect struct {
+	X, Y, Width, Height int32
+}
+
+// Whether p is inside r or on its border
+func (r Rect) contains(p Position) bool {
+	return p.X >= r.X && p.X <= r.X+r.Width && p.Y >= r.Y && p.Y <= r.Y+r.Height
+}
+
+// Orthogonal path through the points, on a grid with lines every step
+// starting at zero. Legs between consecutive points go around the obstacles,
+// crossing them only when there is no other way, such as when leaving the
+// component a node lies on. The result holds the ends of every segment
+func RouteWire(points []Position, obstacles []Rect, step int32) []Position {
+	if len(points) == 0 {
+		return nil
+	}
+	path := []Position{points[0]}
+	for i := 1; i < len(points); i++ {
+		path = append(path, routeLeg(points[i-1], points[i], obstacles, step)...)
+	}
+	return simplifyPath(path)
+}
+
+func roundTo(v, step int32) int32 {
+	return floorDiv(v+step/2, step) * step
+}
+
+// Division rounding towards negative infinity
+func floorDiv(v, step int32) int32 {
+	q := v / step
+	if v%step != 0 && (v < 0) != (step < 0) {
+		q--
+	}
+	return q
+}
+
+// Points after from on the way to to, entering the grid at the closest line
+// crossings to both ends
+func routeLeg(from, to Position, obstacles []Rect, step int32) []Position {
+	start := Position{roundTo(from.X, step), roundTo(from.Y, step)}
+	goal := Position{roundTo(to.X, step), roundTo(to.Y, step)}
+	path := []Position{{start.X, from.Y}, start}
+	path = append(path, searchRoute(start, goal, obstacles, step)...)
+	return append(path, Position{goal.X, to.Y}, to)
+}
+
+type routeDirection int8
+
+const (
+	routeStart routeDirection = iota
+	routeHorizontal
+	routeVertical
+)
+
+type routeState struct {
+	cell      Position
+	direction routeDirection
+}
+
+type routeItem struct {
+	state    routeState
+	cost     int
+	priority int
+}
+
+type routeQueue []routeItem
+
+func (q routeQueue) Len() int           { return len(q) }
+func (q routeQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+func (q routeQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *routeQueue) Push(x any)        { *q = append(*q, x.(routeItem)) }
+func (q *routeQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+func abs(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Cheapest grid path from start to goal by A*, excluding start. Cells are in
+// grid units while searching
+func searchRoute(start, goal Position, obstacles []Rect, step int32) []Position {
+	if start == goal {
+		return nil
+	}
+	cell := func(p Position) Position { return Position{p.X / step, p.Y / step} }
+	from, to := cell(start), cell(goal)
+	minX, maxX := min(from.X, to.X)-RoutingMargin, max(from.X, to.X)+RoutingMargin
+	minY, maxY := min(from.Y, to.Y)-RoutingMargin, max(from.Y, to.Y)+RoutingMargin
+	blocked := func(c Position) bool {
+		p := Position{c.X * step, c.Y * step}
+		for _, obstacle := range obstacles {
+			if obstacle.contains(p) {
+				return true
+			}
+		}
+		return false
+	}
+	heuristic := func(c Position) int {
+		return int(abs(c.X-to.X) + abs(c.Y-to.Y))
+	}
+
+	initial := routeState{from, routeStart}
+	costs := map[routeState]int{initial: 0}
+	previous := map[routeState]routeState{}
+	queue := &routeQueue{{initial, 0, heuristic(from)}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(routeItem)
+		if item.cost > costs[item.state] {
+			continue
+		}
+		if item.state.cell == to {
+			var path []Position
+			for state := item.state; state != initial; state = previous[state] {
+				path = append(path, Position{state.cell.X * step, state.cell.Y * step})
+			}
+			slices.Reverse(path)
+			return path
+		}
+		for _, move := range []Position{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			next := Position{item.state.cell.X + move.X, item.state.cell.Y + move.Y}
+			if next.X < minX || next.X > maxX || next.Y < minY || next.Y > maxY {
+				continue
+			}
+			direction := routeHorizontal
+			if move.X == 0 {
+				direction = routeVertical
+			}
+			cost := item.cost + 1
+			if item.state.direction != routeStart && item.state.direction != direction {
+				cost += bendPenalty
+			}
+			if blocked(next) {
+				cost += obstaclePenalty
+			}
+			state := routeState{next, direction}
+			if known, ok := costs[state]; ok && known <= cost {
+				continue
+			}
+			costs[state] = cost
+			previous[state] = item.state
+			heap.Push(queue, routeItem{state, cost, cost + heuristic(next)})
+		}
+	}
+	// unreachable within the margin, fall back to a straight corner
+	return []Position{{goal.X, start.Y}, goal}
+}
+
+// Drops repeated points and the ones in the middle of straight runs
+func simplifyPath(path []Position) []Position {
+	var simplified []Position
+	for _, p := range path {
+		if n := len(simplified); n > 0 && simplified[n-1] == p {
+			continue
+		}
+		if n := len(simplified); n > 1 {
+			a, b := simplified[n-2], simplified[n-1]
+			if (a.X == b.X && b.X == p.X) || (a.Y == b.Y && b.Y == p.Y) {
+				simplified[n-1] = p
+				continue
+			}
+		}
+		simplified = append(simplified, p)
+	}
+	return simplified
+}
+
+// Whether p lies on the segment from a to b, within tolerance of it
+func onSegment(p, a, b Position, tolerance int32) bool {
+	return p.X >= min(a.X, b.X)-tolerance && p.X <= max(a.X, b.X)+tolerance &&
+		p.Y >= min(a.Y, b.Y)-tolerance && p.Y <= max(a.Y, b.Y)+tolerance
+}
+
+// Whether p lies on the orthogonal path, within tolerance of its segments
+func OnPath(path []Position, p Position, tolerance int32) bool {
+	for i := 1; i < len(path); i++ {
+		if onSegment(p, path[i-1], path[i], tolerance) {
+			return true
+		}
+	}
+	return len(path) == 1 && onSegment(p, path[0], path[0], tolerance)
+}
+
+// Points where three or more of the orthogonal paths of wires in the same
+// net meet, sorted by position
+func Junctions(paths map[*Wire][]Position) []Position {
+	nets := newNetIndex()
+	groups := map[int][][]Position{}
+	for wire, path := range paths {
+		net := nets.of(wire.From)
+		groups[net] = append(groups[net], path)
+	}
+	var junctions []Position
+	for _, group := range groups {
+		junctions = append(junctions, netJunctions(group)...)
+	}
+	slices.SortFunc(junctions, func(a, b Position) int {
+		return cmp.Or(cmp.Compare(a.X, b.X), cmp.Compare(a.Y, b.Y))
+	})
+	return slices.Compact(junctions)
+}
+
+// Points of the paths that three or more of them pass through
+func netJunctions(paths [][]Position) []Position {
+	// the points of every path by the vertical and horizontal lines through
+	// them, so each segment only checks the points in line with it
+	columns, rows := map[int32][]Position{}, map[int32][]Position{}
+	indexed := map[Position]bool{}
+	for _, path := range paths {
+		for _, p := range path {
+			if !indexed[p] {
+				indexed[p] = true
+				columns[p.X] = append(columns[p.X], p)
+				rows[p.Y] = append(rows[p.Y], p)
+			}
+		}
+	}
+
+	meetings := map[Position]int{}
+	for _, path := range paths {
+		on := map[Position]bool{}
+		for i, b := range path {
+			a := path[max(i-1, 0)]
+			if a.X == b.X {
+				for _, p := range columns[a.X] {
+					on[p] = on[p] || p.Y >= min(a.Y, b.Y) && p.Y <= max(a.Y, b.Y)
+				}
+			}
+			if a.Y == b.Y {
+				for _, p := range rows[a.Y] {
+					on[p] = on[p] || p.X >= min(a.X, b.X) && p.X <= max(a.X, b.X)
+				}
+			}
+		}
+		for p, ok := range on {
+			if ok {
+				meetings[p]++
+			}
+		}
+	}
+
+	var junctions []Position
+	for p, count := range meetings {
+		if count >= 3 {
+			junctions = append(junctions, p)
+		}
+	}
+	return junctions
+}
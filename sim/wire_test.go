@@ -0,0 +1,130 @@
+package sim
+
+import (
+	"slices"
+	"testing"
+)
+
+// Whether any point of the path lies on r
+func crosses(path []Position, r Rect) bool {
+	for i := 1; i < len(path); i++ {
+		a, b := path[i-1], path[i]
+		for x := min(a.X, b.X); x <= max(a.X, b.X); x++ {
+			for y := min(a.Y, b.Y); y <= max(a.Y, b.Y); y++ {
+				if r.contains(Position{x, y}) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func TestRouteWire(t *testing.T) {
+	obstacle := Rect{50, 0, 100, 100}
+	tt := []struct {
+		name      string
+		points    []Position
+		obstacles []Rect
+		// expected path, only checked when set
+		expected []Position
+		// obstacles the path must not cross
+		avoid []Rect
+	}{
+		{"straight", []Position{{0, 0}, {100, 0}}, nil, []Position{{0, 0}, {100, 0}}, nil},
+		{"corner", []Position{{0, 0}, {100, 50}}, nil, nil, nil},
+		{"around an obstacle", []Position{{0, 50}, {200, 50}}, []Rect{obstacle}, nil, []Rect{obstacle}},
+		{"through waypoints", []Position{{0, 0}, {50, 150}, {100, 0}}, nil, nil, nil},
+		{"off the grid", []Position{{3, 7}, {104, 56}}, nil, nil, nil},
+		{"leaving its own component", []Position{{100, 50}, {300, 50}}, []Rect{obstacle}, nil, nil},
+	}
+	for _, tc := range tt {
+		path := RouteWire(tc.points, tc.obstacles, 10)
+		if tc.expected != nil && !slices.Equal(path, tc.expected) {
+			t.Errorf("%s: expected path %v but got %v", tc.name, tc.expected, path)
+		}
+		if path[0] != tc.points[0] || path[len(path)-1] != tc.points[len(tc.points)-1] {
+			t.Errorf("%s: path %v does not join the ends", tc.name, path)
+		}
+		for i := 1; i < len(path); i++ {
+			if path[i-1].X != path[i].X && path[i-1].Y != path[i].Y {
+				t.Errorf("%s: segment %v to %v of %v is not orthogonal", tc.name, path[i-1], path[i], path)
+			}
+		}
+		for _, point := range tc.points {
+			if !OnPath(path, point, 0) {
+				t.Errorf("%s: path %v does not pass through %v", tc.name, path, point)
+			}
+		}
+		for _, avoided := range tc.avoid {
+			if crosses(path, avoided) {
+				t.Errorf("%s: path %v crosses the obstacle %v", tc.name, path, avoided)
+			}
+		}
+	}
+}
+
+func TestOnPath(t *testing.T) {
+	path := []Position{{0, 0}, {100, 0}, {100, 100}}
+	tt := []struct {
+		point     Position
+		tolerance int32
+		expected  bool
+	}{
+		{Position{50, 0}, 0, true},
+		{Position{100, 50}, 0, true},
+		{Position{50, 3}, 0, false},
+		{Position{50, 3}, 4, true},
+		{Position{50, 50}, 4, false},
+		{Position{110, 100}, 4, false},
+	}
+	for _, tc := range tt {
+		if actual := OnPath(path, tc.point, tc.tolerance); actual != tc.expected {
+			t.Errorf("expected %v on path within %d to be %v", tc.point, tc.tolerance, tc.expected)
+		}
+	}
+}
+
+func TestJunctions(t *testing.T) {
+	horizontal := []Position{{0, 0}, {100, 0}}
+	up := []Position{{50, 0}, {50, -100}}
+	down := []Position{{50, 0}, {50, 100}}
+	corner := []Position{{100, 0}, {100, 100}}
+
+	// wires of a net start on a, the other net starts on b
+	a, b := NewNode("a"), NewNode("b")
+	tt := []struct {
+		paths    map[*Wire][]Position
+		expected []Position
+	}{
+		{
+			paths: map[*Wire][]Position{
+				NewWire(a, NewNode("")): horizontal,
+				NewWire(a, NewNode("")): up,
+				NewWire(a, NewNode("")): down,
+			},
+			expected: []Position{{50, 0}},
+		},
+		{
+			// two wires meeting is a corner, not a junction
+			paths: map[*Wire][]Position{
+				NewWire(a, NewNode("")): horizontal,
+				NewWire(a, NewNode("")): up,
+				NewWire(a, NewNode("")): corner,
+			},
+		},
+		{
+			// wires of different nets only cross
+			paths: map[*Wire][]Position{
+				NewWire(a, NewNode("")): horizontal,
+				NewWire(a, NewNode("")): up,
+				NewWire(b, NewNode("")): down,
+			},
+		},
+	}
+	for _, tc := range tt {
+		if junctions := Junctions(tc.paths); !slices.Equal(junctions, tc.expected) {
+			t.Errorf("expected junctions %v but got %v", tc.expected, junctions)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Bit of a Yosys signal, either a net number or one of the constants "0",
+// "1", "x" and "z"
+type yosysBit struct {
+	net      int
+	constant string
+}
+
+func (b *yosysBit) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &b.net); err == nil {
+		return nil
+	}
+	return json.Unmarshal(data, &b.constant)
+}
+
+type yosysPort struct {
+	Name      string
+	Direction string     `json:"direction"`
+	Bits      []yosysBit `json:"bits"`
+}
+
+// Ports keep the order of the file, which is the order of the Verilog module
+type yosysPorts []yosysPort
+
+func (p *yosysPorts) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	for decoder.More() {
+		name, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		port := yosysPort{Name: fmt.Sprint(name)}
+		if err := decoder.Decode(&port); err != nil {
+			return err
+		}
+		*p = append(*p, port)
+	}
+	return nil
+}
+
+type yosysCell struct {
+	Type        string                `json:"type"`
+	Connections map[string][]yosysBit `json:"connections"`
+}
+
+type yosysNetname struct {
+	HideName   int            `json:"hide_name"`
+	Bits       []yosysBit     `json:"bits"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+type yosysModule struct {
+	Attributes map[string]any          `json:"attributes"`
+	Ports      yosysPorts              `json:"ports"`
+	Cells      map[string]yosysCell    `json:"cells"`
+	Netnames   map[string]yosysNetname `json:"netnames"`
+}
+
+type yosysDesign struct {
+	Modules map[string]yosysModule `json:"modules"`
+}
+
+func yosysBitName(name string, bits []yosysBit, i int) string {
+	if len(bits) == 1 {
+		return name
+	}
+	return fmt.Sprintf("%s[%d]", name, i)
+}
+
+// Picks the module marked as top, or the only module of the design
+func (d yosysDesign) top() (string, yosysModule, error) {
+	var names []string
+	for name, module := range d.Modules {
+		if _, ok := module.Attributes["top"]; ok {
+			return name, module, nil
+		}
+		names = append(names, name)
+	}
+	if len(names) != 1 {
+		slices.Sort(names)
+		return "", yosysModule{}, fmt.Errorf("design has modules %v and none is marked as top", names)
+	}
+	return names[0], d.Modules[names[0]], nil
+}
+
+type yosysImporter struct {
+	netlist *Netlist
+	names   map[int]string
+}
+
+func (y *yosysImporter) node(bit yosysBit) (*Node, error) {
+	switch bit.constant {
+	case "":
+		if name, ok := y.names[bit.net]; ok {
+			return y.netlist.net(name), nil
+		}
+		return y.netlist.net(fmt.Sprintf("n%d", bit.net)), nil
+	case "0":
+		return y.netlist.constant(Off), nil
+	case "1":
+		return y.netlist.constant(On), nil
+	}
+	return nil, fmt.Errorf("constant %s bits are not supported", bit.constant)
+}
+
+// Has both latches of a flip-flop built by NewDFlipFlop hold state, as if
+// stored on the previous clock edge
+func presetDFlipFlop(flipFlop *CustomComponent, state NodeState) {
+	for _, latch := range flipFlop.Subcomponents[1:] {
+		for node := range ConnectedNodes(latch.(*CustomComponent).Outputs[0]) {
+			node.held = state
+		}
+	}
+}
+
+func (y *yosysImporter) cell(name string, cell yosysCell, initial map[int]NodeState) error {
+	pin := func(port string) (*Node, error) {
+		bits := cell.Connections[port]
+		if len(bits) != 1 {
+			return nil, fmt.Errorf("cell %s of type %s has %d bits on port %s", name, cell.Type, len(bits), port)
+		}
+		return y.node(bits[0])
+	}
+	pins := func(ports ...string) ([]*Node, error) {
+		var nodes []*Node
+		for _, port := range ports {
+			node, err := pin(port)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes, nil
+	}
+
+	n := y.netlist
+	var out *Node
+	output := "Y"
+	switch cell.Type {
+	case "$_BUF_", "$_NOT_":
+		in, err := pins("A")
+		if err != nil {
+			return err
+		}
+		out = in[0]
+		if cell.Type == "$_NOT_" {
+			out = n.not(in[0])
+		}
+	case "$_AND_", "$_OR_", "$_XOR_", "$_NAND_", "$_NOR_", "$_XNOR_", "$_ANDNOT_", "$_ORNOT_":
+		in, err := pins("A", "B")
+		if err != nil {
+			return err
+		}
+		switch cell.Type {
+		case "$_AND_":
+			out = n.chain(in, NewAndGate)
+		case "$_OR_":
+			out = n.chain(in, NewOrGate)
+		case "$_XOR_":
+			out = n.chain(in, NewXorGate)
+		case "$_NAND_":
+			out = n.chain(in, NewNandGate)
+		case "$_NOR_":
+			out = n.not(n.chain(in, NewOrGate))
+		case "$_XNOR_":
+			out = n.not(n.chain(in, NewXorGate))
+		case "$_ANDNOT_":
+			out = n.chain([]*Node{in[0], n.not(in[1])}, NewAndGate)
+		case "$_ORNOT_":
+			out = n.chain([]*Node{in[0], n.not(in[1])}, NewOrGate)
+		}
+	case "$_MUX_":
+		// Y = S ? B : A
+		in, err := pins("A", "B", "S")
+		if err != nil {
+			return err
+		}
+		a := n.chain([]*Node{in[0], n.not(in[2])}, NewAndGate)
+		b := n.chain([]*Node{in[1], in[2]}, NewAndGate)
+		out = n.chain([]*Node{a, b}, NewOrGate)
+	case "$_DFF_P_", "$_DFF_N_":
+		in, err := pins("C", "D")
+		if err != nil {
+			return err
+		}
+		clock := in[0]
+		if cell.Type == "$_DFF_N_" {
+			clock = n.not(clock)
+		}
+		var flipFlop *CustomComponent
+		out, flipFlop = NewDFlipFlop(clock, in[1])
+		if bits := cell.Connections["Q"]; len(bits) == 1 {
+			if state, ok := initial[bits[0].net]; ok && bits[0].constant == "" {
+				presetDFlipFlop(flipFlop, state)
+			}
+		}
+		n.Components = append(n.Components, flipFlop)
+		output = "Q"
+	default:
+		return fmt.Errorf("cell %s has unsupported type %s, synthesise with synth -flatten to get simple gates", name, cell.Type)
+	}
+	driven, err := pin(output)
+	if err != nil {
+		return err
+	}
+	driven.Connect(out)
+	return nil
+}
+
+// Imports the top module of a flattened Yosys JSON netlist, as written by
+// write_json after synthesis to internal gate cells. Multi-bit ports are
+// split into one input or output per bit, named like a[0]
+func ImportYosysJSON(r io.Reader) (*Netlist, error) {
+	var design yosysDesign
+	if err := json.NewDecoder(r).Decode(&design); err != nil {
+		return nil, err
+	}
+	name, module, err := design.top()
+	if err != nil {
+		return nil, err
+	}
+
+	y := &yosysImporter{netlist: newNetlist(), names: map[int]string{}}
+	y.netlist.Name = name
+
+	// ports name their nets first, then visible net names, then hidden ones
+	for _, port := range module.Ports {
+		for i, bit := range port.Bits {
+			if _, ok := y.names[bit.net]; !ok && bit.constant == "" {
+				y.names[bit.net] = yosysBitName(port.Name, port.Bits, i)
+			}
+		}
+	}
+	var netnames []string
+	for netname := range module.Netnames {
+		netnames = append(netnames, netname)
+	}
+	slices.Sort(netnames)
+	slices.SortStableFunc(netnames, func(a, b string) int {
+		return module.Netnames[a].HideName - module.Netnames[b].HideName
+	})
+	initial := map[int]NodeState{}
+	for _, netname := range netnames {
+		net := module.Netnames[netname]
+		init, _ := net.Attributes["init"].(string)
+		for i, bit := range net.Bits {
+			if bit.constant != "" {
+				continue
+			}
+			if _, ok := y.names[bit.net]; !ok {
+				y.names[bit.net] = yosysBitName(netname, net.Bits, i)
+			}
+			if i < len(init) && (init[len(init)-1-i] == '0' || init[len(init)-1-i] == '1') {
+				initial[bit.net] = boolToState(init[len(init)-1-i] == '1')
+			}
+		}
+	}
+
+	for _, port := range module.Ports {
+		for i, bit := range port.Bits {
+			portName := yosysBitName(port.Name, port.Bits, i)
+			switch port.Direction {
+			case "input":
+				y.netlist.Inputs = append(y.netlist.Inputs, portName)
+			case "output":
+				y.netlist.Outputs = append(y.netlist.Outputs, portName)
+			default:
+				return nil, fmt.Errorf("port %s has unsupported direction %s", port.Name, port.Direction)
+			}
+			// constant outputs and ports sharing a net with another port
+			if bit.constant != "" || y.names[bit.net] != portName {
+				node, err := y.node(bit)
+				if err != nil {
+					return nil, fmt.Errorf("port %s: %w", portName, err)
+				}
+				y.netlist.net(portName).Connect(node)
+			}
+		}
+	}
+
+	var cells []string
+	for cell := range module.Cells {
+		cells = append(cells, cell)
+	}
+	slices.Sort(cells)
+	for _, cell := range cells {
+		if err := y.cell(cell, module.Cells[cell], initial); err != nil {
+			return nil, err
+		}
+	}
+	return y.netlist.finish(), nil
+}
@@ -0,0 +1,161 @@
+package sim
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportYosysJSON(t *testing.T) {
+	// full adder with a multiplexed carry, as written by yosys write_json
+	design := `{
+  "creator": "Yosys",
+  "modules": {
+    "full_adder": {
+      "attributes": {"top": "00000000000000000000000000000001"},
+      "ports": {
+        "a": {"direction": "input", "bits": [2]},
+        "b": {"direction": "input", "bits": [3]},
+        "cin": {"direction": "input", "bits": [4]},
+        "out": {"direction": "output", "bits": [5, 6, "1"]}
+      },
+      "cells": {
+        "$abc$1": {"type": "$_XOR_", "connections": {"A": [2], "B": [3], "Y": [7]}},
+        "$abc$2": {"type": "$_XOR_", "connections": {"A": [7], "B": [4], "Y": [5]}},
+        "$abc$3": {"type": "$_MUX_", "connections": {"A": [2], "B": [4], "S": [7], "Y": [6]}}
+      },
+      "netnames": {
+        "$abc$x": {"hide_name": 1, "bits": [7], "attributes": {}},
+        "p": {"hide_name": 0, "bits": [7], "attributes": {}}
+      }
+    }
+  }
+}`
+	netlist, err := ImportYosysJSON(strings.NewReader(design))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedInputs := []string{"a", "b", "cin"}
+	expectedOutputs := []string{"out[0]", "out[1]", "out[2]"}
+	if strings.Join(netlist.Inputs, ",") != strings.Join(expectedInputs, ",") || strings.Join(netlist.Outputs, ",") != strings.Join(expectedOutputs, ",") {
+		t.Fatalf("expected ports %v %v but got %v %v", expectedInputs, expectedOutputs, netlist.Inputs, netlist.Outputs)
+	}
+	if _, ok := netlist.Nets["p"]; !ok {
+		t.Errorf("expected the internal net to be named after its visible net name")
+	}
+	checkNetlist(t, netlist, func(inputs []bool) []bool {
+		a, b, cin := inputs[0], inputs[1], inputs[2]
+		return []bool{a != b != cin, a && b || cin && (a != b), true}
+	})
+	if ConnectedNodes(netlist.Nets["out[2]"])[SharedSourceNode] {
+		t.Errorf("expected the constant output to be driven by the netlist instead of the shared supply")
+	}
+}
+
+func TestImportYosysJSONFlipFlops(t *testing.T) {
+	// two bit counter, q[0] starts on through its init attribute
+	design := `{
+  "modules": {
+    "counter": {
+      "ports": {
+        "clk": {"direction": "input", "bits": [2]},
+        "q": {"direction": "output", "bits": [3, 4]}
+      },
+      "cells": {
+        "d0": {"type": "$_NOT_", "connections": {"A": [3], "Y": [5]}},
+        "d1": {"type": "$_XOR_", "connections": {"A": [3], "B": [4], "Y": [6]}},
+        "q0": {"type": "$_DFF_P_", "connections": {"C": [2], "D": [5], "Q": [3]}},
+        "q1": {"type": "$_DFF_P_", "connections": {"C": [2], "D": [6], "Q": [4]}}
+      },
+      "netnames": {
+        "q": {"hide_name": 0, "bits": [3, 4], "attributes": {"init": "01"}}
+      }
+    }
+  }
+}`
+	netlist, err := ImportYosysJSON(strings.NewReader(design))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for cycle, expected := range []int{2, 3, 0, 1, 2} {
+		for _, clock := range []NodeState{Off, On} {
+			if err := netlist.SetInput("clk", clock); err != nil {
+				t.Fatal(err)
+			}
+			if err := netlist.Circuit.Tick(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		count := 0
+		for i, output := range netlist.Outputs {
+			state, _ := netlist.Output(output)
+			if state == On {
+				count |= 1 << i
+			}
+		}
+		if count != expected {
+			t.Errorf("expected count %d after %d clock cycles but got %d", expected, cycle+1, count)
+		}
+	}
+}
+
+func TestImportYosysJSONFallingEdgeFlipFlop(t *testing.T) {
+	design := `{
+  "modules": {
+    "register": {
+      "ports": {
+        "clk": {"direction": "input", "bits": [2]},
+        "d": {"direction": "input", "bits": [3]},
+        "q": {"direction": "output", "bits": [4]}
+      },
+      "cells": {
+        "q": {"type": "$_DFF_N_", "connections": {"C": [2], "D": [3], "Q": [4]}}
+      }
+    }
+  }
+}`
+	netlist, err := ImportYosysJSON(strings.NewReader(design))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := []struct {
+		clock, data NodeState
+		expected    NodeState
+	}{
+		{clock: On, data: On, expected: Off},
+		// falling edge, storing the data of the step before
+		{clock: Off, data: Off, expected: On},
+		{clock: On, data: Off, expected: On},
+		{clock: Off, data: On, expected: Off},
+	}
+	for i, tc := range tt {
+		if err := netlist.SetInput("clk", tc.clock); err != nil {
+			t.Fatal(err)
+		}
+		if err := netlist.SetInput("d", tc.data); err != nil {
+			t.Fatal(err)
+		}
+		if err := netlist.Circuit.Tick(); err != nil {
+			t.Fatal(err)
+		}
+		if state, _ := netlist.Output("q"); state != tc.expected {
+			t.Errorf("step %d: expected q to be %s but got %s", i, tc.expected, state)
+		}
+	}
+}
+
+func TestImportYosysJSONErrors(t *testing.T) {
+	tt := []struct {
+		design        string
+		expectedError string
+	}{
+		{`{"modules": {"a": {}, "b": {}}}`, "design has modules [a b] and none is marked as top"},
+		{`{"modules": {"m": {"cells": {"c": {"type": "$_SR_PP_", "connections": {}}}}}}`, "cell c has unsupported type $_SR_PP_, synthesise with synth -flatten to get simple gates"},
+		{`{"modules": {"m": {"cells": {"c": {"type": "$_NOT_", "connections": {"A": ["x"], "Y": [2]}}}}}}`, "constant x bits are not supported"},
+	}
+	for _, tc := range tt {
+		_, err := ImportYosysJSON(strings.NewReader(tc.design))
+		if err == nil || err.Error() != tc.expectedError {
+			t.Errorf("expected error %q but got %v", tc.expectedError, err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/murilo-toddy/copooter/render"
+	"github.com/murilo-toddy/copooter/sim"
+)
+
+var (
+	// Distance from a wire within which clicking selects it
+	wireClickTolerance = render.WireWidth + 2
+	selectedWireColor  = rl.SkyBlue
+	pendingWireColor   = rl.NewColor(102, 191, 255, 120)
+)
+
+// Paths of the routed wires, routed again only when the layout changes
+type wireRoutes struct {
+	layout    string
+	paths     map[*sim.Wire][]sim.Position
+	junctions []sim.Position
+}
+
+// Creates a wire for every connection between placed nodes that has none,
+// such as the ones of pasted components, and drops the wires whose
+// connection is gone. Internal nodes of blocks have no position on the
+// schematic and get no wires
+func syncWires(s *DrawingState) {
+	placed := map[*sim.Node]bool{}
+	for _, component := range s.components {
+		for _, node := range component.Nodes() {
+			placed[node] = true
+		}
+	}
+	// connections are counted from both of their nodes
+	unwired := map[[2]*sim.Node]int{}
+	for _, component := range s.components {
+		for _, node := range component.Nodes() {
+			for _, conn := range node.Connections() {
+				if placed[conn] {
+					unwired[[2]*sim.Node{node, conn}]++
+				}
+			}
+		}
+	}
+	s.wires = slices.DeleteFunc(s.wires, func(wire *sim.Wire) bool {
+		if unwired[[2]*sim.Node{wire.From, wire.To}] == 0 {
+			return true
+		}
+		unwired[[2]*sim.Node{wire.From, wire.To}]--
+		unwired[[2]*sim.Node{wire.To, wire.From}]--
+		return false
+	})
+	for _, component := range s.components {
+		for _, node := range component.Nodes() {
+			for _, conn := range node.Connections() {
+				if unwired[[2]*sim.Node{node, conn}] > 0 {
+					unwired[[2]*sim.Node{node, conn}]--
+					unwired[[2]*sim.Node{conn, node}]--
+					s.wires = append(s.wires, sim.NewWire(node, conn))
+				}
+			}
+		}
+	}
+	if s.selectedWire != nil && !slices.Contains(s.wires, s.selectedWire) {
+		clearSelection(s)
+	}
+}
+
+// Routing grid lines start at the toolkit sidebar, like the schematic grid
+func toRoutingGrid(p sim.Position) sim.Position {
+	return sim.Position{X: p.X - toolkitSidebarSize, Y: p.Y}
+}
+
+func fromRoutingGrid(p sim.Position) sim.Position {
+	return sim.Position{X: p.X + toolkitSidebarSize, Y: p.Y}
+}
+
+func nodePosition(n *sim.Node) sim.Position {
+	x, y := render.TerminalCoordinates(n)
+	return sim.Position{X: int32(x), Y: int32(y)}
+}
+
+// Bodies of the placed components along with their names
+func componentObstacles(s *DrawingState) (obstacles []sim.Rect) {
+	for _, component := range s.components {
+		p := toRoutingGrid(component.GetID().Position)
+		obstacles = append(obstacles, sim.Rect{
+			X:      p.X,
+			Y:      p.Y,
+			Width:  render.ComponentImageSize,
+			Height: render.ComponentImageSize + render.ComponentFontSize,
+		})
+	}
+	return
+}
+
+// Orthogonal path from the start through the points, around the components
+func routePath(points []sim.Position, obstacles []sim.Rect) []sim.Position {
+	var grid []sim.Position
+	for _, p := range points {
+		grid = append(grid, toRoutingGrid(p))
+	}
+	path := sim.RouteWire(grid, obstacles, render.GridCellSize)
+	for i := range path {
+		path[i] = fromRoutingGrid(path[i])
+	}
+	return path
+}
+
+func wirePoints(wire *sim.Wire) []sim.Position {
+	points := []sim.Position{nodePosition(wire.From)}
+	points = append(points, wire.Waypoints...)
+	return append(points, nodePosition(wire.To))
+}
+
+// Describes where components and wires are, routes only change along with it
+func wireLayout(s *DrawingState) string {
+	var layout strings.Builder
+	for _, component := range s.components {
+		id := component.GetID()
+		fmt.Fprint(&layout, id.Position, id.Orientation)
+	}
+	for _, wire := range s.wires {
+		fmt.Fprintf(&layout, "%p%p%v", wire.From, wire.To, wire.Waypoints)
+	}
+	return layout.String()
+}
+
+func updateWireRoutes(s *DrawingState) {
+	layout := wireLayout(s)
+	if s.routes.paths != nil && layout == s.routes.layout {
+		return
+	}
+	obstacles := componentObstacles(s)
+	s.routes = wireRoutes{layout: layout, paths: map[*sim.Wire][]sim.Position{}}
+	for _, wire := range s.wires {
+		s.routes.paths[wire] = routePath(wirePoints(wire), obstacles)
+	}
+	s.routes.junctions = sim.Junctions(s.routes.paths)
+}
+
+// Wire under the screen position, the last drawn when wires overlap
+func wireAt(s *DrawingState, pos rl.Vector2) *sim.Wire {
+	world := screenToWorld(pos)
+	p := sim.Position{X: int32(world.X), Y: int32(world.Y)}
+	for i := len(s.wires) - 1; i >= 0; i-- {
+		if sim.OnPath(s.routes.paths[s.wires[i]], p, wireClickTolerance) {
+			return s.wires[i]
+		}
+	}
+	return nil
+}
+
+func selectWire(s *DrawingState, wire *sim.Wire) {
+	clearSelection(s)
+	s.selectedWire = wire
+	s.state = StateWireSelected
+}
+
+func checkDeleteWire(s *DrawingState) {
+	if rl.IsKeyPressed(rl.KeyDelete) || rl.IsKeyPressed(rl.KeyBackspace) {
+		s.history.Execute(s, &deleteWireCommand{s.selectedWire})
+		clearSelection(s)
+	}
+}
+
+// Grid crossing closest to the screen position, where waypoints are placed
+func snapWaypoint(pos rl.Vector2) sim.Position {
+	world := screenToWorld(pos)
+	half := render.GridCellSize / 2
+	x, y := snapCoordinates(int32(world.X)+half, int32(world.Y)+half)
+	return sim.Position{X: x, Y: y}
+}
+
+// Right clicking drops the last waypoint placed for the wire being drawn,
+// then the selected node
+func checkCancelWire(s *DrawingState) {
+	if !rl.IsMouseButtonPressed(rl.MouseButtonRight) {
+		return
+	}
+	if len(s.waypoints) > 0 {
+		s.waypoints = s.waypoints[:len(s.waypoints)-1]
+	} else {
+		clearSelection(s)
+	}
+}
+
+func drawWires(s DrawingState) {
+	for _, wire := range s.wires {
+		color := render.NodeStateColor(wire.From)
+		if wire == s.selectedWire {
+			color = selectedWireColor
+		}
+		render.DrawWirePath(s.routes.paths[wire], color)
+	}
+	for _, junction := range s.routes.junctions {
+		render.DrawJunction(junction, rl.White)
+	}
+}
+
+// Previews the wire being drawn from the selected node to the mouse
+func drawPendingWire(s DrawingState, pos rl.Vector2) {
+	node := (*s.selectedComponent).Nodes()[*s.selectedNode]
+	points := append([]sim.Position{nodePosition(node)}, s.waypoints...)
+	points = append(points, snapWaypoint(pos))
+	render.DrawWirePath(routePath(points, componentObstacles(&s)), pendingWireColor)
+	for _, waypoint := range s.waypoints {
+		render.DrawJunction(waypoint, pendingWireColor)
+	}
+}